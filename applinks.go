@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Sources of an AppLink
+const (
+	AppLinkSourceAppLinks = "al"
+	AppLinkSourceITunes   = "apple-itunes-app"
+	AppLinkSourceAndroid  = "android-app"
+)
+
+// AppLink is a way into a native app for one platform
+type AppLink struct {
+	// Platform is ios, iphone, ipad, android, windows, windows_phone or windows_universal
+	Platform string `json:"platform"`
+	// URL is the deep link opening the content in the app
+	URL        string `json:"url"`
+	AppName    string `json:"app_name"`
+	AppStoreID string `json:"app_store_id"`
+	Package    string `json:"package"`
+	Class      string `json:"class"`
+	AppID      string `json:"app_id"`
+	// Source is al for App Links tags, apple-itunes-app or android-app
+	Source string `json:"source"`
+}
+
+// AppLinks holds the native apps declared by the page, in document order
+type AppLinks struct {
+	Apps   []*AppLink `json:"apps"`
+	WebURL string     `json:"web_url"`
+	// WebShouldFallback is al:web:should_fallback as written, see ShouldFallback
+	WebShouldFallback string `json:"web_should_fallback"`
+}
+
+// ShouldFallback reports whether a client without the app should open the web page, true unless declared otherwise
+func (links *AppLinks) ShouldFallback() bool {
+	switch strings.ToLower(strings.TrimSpace(links.WebShouldFallback)) {
+	case "false", "0":
+		return false
+	}
+	return true
+}
+
+// Lookup returns the first app of the first platform that has one, e.g. Lookup("iphone", "ios")
+func (links *AppLinks) Lookup(platforms ...string) *AppLink {
+	for _, platform := range platforms {
+		for _, app := range links.Apps {
+			if app.Platform == platform {
+				return app
+			}
+		}
+	}
+	return nil
+}
+
+func isAppLinkProperty(property string) bool {
+	return strings.HasPrefix(property, "al:")
+}
+
+// appLink returns the App Links entry of platform to set field on, a repeated field starts a new entry
+func (result *Result) appLink(platform string, isSet func(*AppLink) bool) *AppLink {
+	for i := len(result.AppLinks.Apps) - 1; i >= 0; i-- {
+		app := result.AppLinks.Apps[i]
+		if app.Platform == platform && app.Source == AppLinkSourceAppLinks {
+			if !isSet(app) {
+				return app
+			}
+			break
+		}
+	}
+	app := &AppLink{Platform: platform, Source: AppLinkSourceAppLinks}
+	result.AppLinks.Apps = append(result.AppLinks.Apps, app)
+	return app
+}
+
+func (result *Result) parseAppLinkMeta(attrs map[string]string) {
+	content := strings.TrimSpace(attrs["content"])
+	// al:<platform>:<field>
+	parts := strings.SplitN(strings.TrimPrefix(attrs["property"], "al:"), ":", 2)
+	if len(parts) != 2 || content == "" {
+		return
+	}
+	platform, field := parts[0], parts[1]
+
+	if platform == "web" {
+		switch field {
+		case "url":
+			result.AppLinks.WebURL = content
+		case "should_fallback":
+			result.AppLinks.WebShouldFallback = content
+		}
+		return
+	}
+
+	switch field {
+	case "url":
+		result.appLink(platform, func(app *AppLink) bool { return app.URL != "" }).URL = content
+	case "app_name":
+		result.appLink(platform, func(app *AppLink) bool { return app.AppName != "" }).AppName = content
+	case "app_store_id":
+		result.appLink(platform, func(app *AppLink) bool { return app.AppStoreID != "" }).AppStoreID = content
+	case "package":
+		result.appLink(platform, func(app *AppLink) bool { return app.Package != "" }).Package = content
+	case "class":
+		result.appLink(platform, func(app *AppLink) bool { return app.Class != "" }).Class = content
+	case "app_id":
+		result.appLink(platform, func(app *AppLink) bool { return app.AppID != "" }).AppID = content
+	}
+}
+
+// parseITunesAppMeta reads the Safari smart banner, <meta name="apple-itunes-app" content="app-id=123, app-argument=...">
+func (result *Result) parseITunesAppMeta(attrs map[string]string) {
+	app := &AppLink{Platform: "ios", Source: AppLinkSourceITunes}
+	for _, param := range strings.Split(attrs["content"], ",") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "app-id":
+			app.AppStoreID = strings.TrimSpace(kv[1])
+		case "app-argument":
+			app.URL = strings.TrimSpace(kv[1])
+		}
+	}
+	if app.AppStoreID != "" {
+		result.AppLinks.Apps = append(result.AppLinks.Apps, app)
+	}
+}
+
+// parseAndroidAppLink reads android-app://<package>/<scheme>/<host_path> alternates
+func (result *Result) parseAndroidAppLink(href string) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || u.Host == "" {
+		return
+	}
+	app := &AppLink{Platform: "android", Package: u.Host, Source: AppLinkSourceAndroid}
+	if parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2); parts[0] != "" {
+		app.URL = parts[0] + "://"
+		if len(parts) == 2 {
+			app.URL += parts[1]
+		}
+		if u.RawQuery != "" {
+			app.URL += "?" + u.RawQuery
+		}
+		if u.Fragment != "" {
+			app.URL += "#" + u.Fragment
+		}
+	}
+	result.AppLinks.Apps = append(result.AppLinks.Apps, app)
+}
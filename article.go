@@ -10,19 +10,19 @@ type Article struct {
 	Authors        []string `json:"authors"`
 }
 
-func (p *Parser) parseArticleMeta(attrs map[string]string) {
+func (result *Result) parseArticleMeta(attrs map[string]string) {
 	switch attrs["property"] {
 	case "article:published_time":
-		p.Article.PublishedTime = attrs["content"]
+		result.Article.PublishedTime = attrs["content"]
 	case "article:modified_time":
-		p.Article.ModifiedTime = attrs["content"]
+		result.Article.ModifiedTime = attrs["content"]
 	case "article:expiration_time":
-		p.Article.ExpirationTime = attrs["content"]
+		result.Article.ExpirationTime = attrs["content"]
 	case "article:section":
-		p.Article.Section = attrs["content"]
+		result.Article.Section = attrs["content"]
 	case "article:author":
-		p.Article.Authors = append(p.Article.Authors, attrs["content"])
+		result.Article.Authors = append(result.Article.Authors, attrs["content"])
 	case "article:tag":
-		p.Article.Tags = append(p.Article.Tags, attrs["content"])
+		result.Article.Tags = append(result.Article.Tags, attrs["content"])
 	}
 }
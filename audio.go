@@ -7,26 +7,26 @@ type Audio struct {
 	Type      string `json:"type"`
 }
 
-func (p *Parser) ensureAudio() {
-	if len(p.Audios) > 0 {
+func (result *Result) ensureAudio() {
+	if len(result.Audios) > 0 {
 		return
 	}
-	p.Audios = append(p.Audios, &Audio{})
+	result.Audios = append(result.Audios, &Audio{})
 }
 
-func (p *Parser) parseAudioMeta(attrs map[string]string) {
+func (result *Result) parseAudioMeta(attrs map[string]string) {
 	switch attrs["property"] {
 	case "og:audio":
-		if len(p.Audios) > 0 && len(p.Audios[len(p.Audios)-1].URL) == 0 {
-			p.Audios[len(p.Audios)-1].URL = attrs["content"]
+		if len(result.Audios) > 0 && len(result.Audios[len(result.Audios)-1].URL) == 0 {
+			result.Audios[len(result.Audios)-1].URL = attrs["content"]
 		} else {
-			p.Audios = append(p.Audios, &Audio{URL: attrs["content"]})
+			result.Audios = append(result.Audios, &Audio{URL: attrs["content"]})
 		}
 	case "og:audio:secure_url":
-		p.ensureAudio()
-		p.Audios[len(p.Audios)-1].SecureURL = attrs["content"]
+		result.ensureAudio()
+		result.Audios[len(result.Audios)-1].SecureURL = attrs["content"]
 	case "og:audio:type":
-		p.ensureAudio()
-		p.Audios[len(p.Audios)-1].Type = attrs["content"]
+		result.ensureAudio()
+		result.Audios[len(result.Audios)-1].Type = attrs["content"]
 	}
 }
@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultBatchWorkers = 8
+	defaultBatchPerHost = 2
+)
+
+// BatchOptions configures BatchParse, zero values select the defaults
+type BatchOptions struct {
+	// Workers is the number of pages parsed at the same time, 8 by default
+	Workers int
+	// PerHost is the number of pages of a single host parsed at the same time, 2 by default
+	PerHost int
+	// Options configure the parser used for every page
+	Options []Option
+}
+
+// BatchResult is the outcome of one URL of a batch
+type BatchResult struct {
+	URL    string
+	Result *Result
+	Err    error
+}
+
+// BatchParse parses urls concurrently and sends each result on the returned channel as soon as
+// it is ready, in completion order. The channel is closed once every URL is done. Identical URLs
+// are parsed and reported once, and a cancelled ctx fails the URLs that have not started yet.
+func BatchParse(ctx context.Context, urls []string, opts BatchOptions) <-chan *BatchResult {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultBatchWorkers
+	}
+	if opts.PerHost <= 0 {
+		opts.PerHost = defaultBatchPerHost
+	}
+
+	var unique []string
+	seen := make(map[string]bool)
+	for _, target := range urls {
+		target = strings.TrimSpace(target)
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		unique = append(unique, target)
+	}
+
+	p := New(opts.Options...)
+	// Buffered so that workers never block on a caller that stopped reading
+	results := make(chan *BatchResult, len(unique))
+	jobs := make(chan string)
+	// Receives the host of every finished job
+	done := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers && i < len(unique); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				result, err := p.ParseURL(ctx, target)
+				results <- &BatchResult{URL: target, Result: result, Err: err}
+				done <- batchHost(target)
+			}
+		}()
+	}
+
+	go func() {
+		batchDispatch(ctx, unique, opts.PerHost, jobs, done, results)
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// batchDispatch hands the URLs to the workers in order, skipping those whose host is busy,
+// so that a slow host never holds a worker that another host could use
+func batchDispatch(ctx context.Context, pending []string, perHost int, jobs chan<- string, done <-chan string, results chan<- *BatchResult) {
+	active := make(map[string]int)
+	running := 0
+	cancelled := ctx.Done()
+	for len(pending) > 0 || running > 0 {
+		next := -1
+		for i, target := range pending {
+			if active[batchHost(target)] < perHost {
+				next = i
+				break
+			}
+		}
+		// A nil channel disables the case while no URL is ready
+		var send chan<- string
+		var target string
+		if next >= 0 {
+			send, target = jobs, pending[next]
+		}
+
+		select {
+		case send <- target:
+			active[batchHost(target)]++
+			running++
+			pending = append(pending[:next:next], pending[next+1:]...)
+		case host := <-done:
+			active[host]--
+			running--
+		case <-cancelled:
+			// URLs that have not started fail, the running ones stop on their own
+			for _, target := range pending {
+				results <- &BatchResult{URL: target, Err: ctx.Err()}
+			}
+			pending, cancelled = nil, nil
+		}
+	}
+}
+
+func batchHost(target string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
@@ -0,0 +1,96 @@
+package parser_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	parser "github.com/ammit/go-metaparser"
+)
+
+func TestBatchParse(t *testing.T) {
+	var mu sync.Mutex
+	active, maxActive, requests := 0, 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		requests++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		} else {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(titleHtml))
+		}
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	urls := []string{ts.URL + "/a", ts.URL + "/b", ts.URL + "/c", ts.URL + "/a", " " + ts.URL + "/b ", ts.URL + "/missing"}
+	results := parser.BatchParse(context.Background(), urls, parser.BatchOptions{Workers: 4, PerHost: 2})
+
+	got := make(map[string]*parser.BatchResult)
+	for result := range results {
+		if got[result.URL] != nil {
+			t.Errorf("%s reported twice", result.URL)
+		}
+		got[result.URL] = result
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 4 || requests != 4 {
+		t.Errorf("identical urls not de-duplicated: %d results, %d requests", len(got), requests)
+	}
+
+	if maxActive > 2 {
+		t.Errorf("per host limit not applied: %d concurrent requests", maxActive)
+	}
+
+	if r := got[ts.URL+"/a"]; r == nil || r.Err != nil || r.Result.Title != "Go Meta Parser" {
+		t.Errorf("batch result parsed incorrectly: %+v", r)
+	}
+
+	if r := got[ts.URL+"/missing"]; r == nil || r.Err == nil || r.Result != nil {
+		t.Errorf("batch error not reported: %+v", r)
+	}
+}
+
+func TestBatchParseBusyHost(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(titleHtml))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(titleHtml))
+	}))
+	defer fast.Close()
+
+	var urls []string
+	for _, path := range []string{"/1", "/2", "/3", "/4", "/5", "/6"} {
+		urls = append(urls, slow.URL+path)
+	}
+	urls = append(urls, fast.URL)
+
+	results := parser.BatchParse(context.Background(), urls, parser.BatchOptions{Workers: 4, PerHost: 1})
+	first := <-results
+	if first.URL != fast.URL {
+		t.Errorf("a busy host must not starve the others, %s came first", first.URL)
+	}
+	for range results {
+	}
+}
@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// minImageSize is the width and height below which an image is an icon or a spacer
+	minImageSize = 200
+	// minLeadLength is the number of characters a paragraph needs to be the lead
+	minLeadLength = 60
+)
+
+// HeuristicImage is an image found in the body, with its declared size
+type HeuristicImage struct {
+	URL    string `json:"url"`
+	Width  int64  `json:"width"`
+	Height int64  `json:"height"`
+	Alt    string `json:"alt"`
+}
+
+// HeuristicMedia is a <video> or <audio> source found in the body
+type HeuristicMedia struct {
+	URL  string `json:"url"`
+	Type string `json:"type"`
+}
+
+// Heuristics holds what WithBodyScan guessed from the content of the page. Unlike the other
+// fields of Result it is not metadata declared by the page, so it may be wrong.
+type Heuristics struct {
+	// Heading is the text of the first non-empty <h1>
+	Heading string `json:"heading"`
+	// Lead is the first paragraph long enough to describe the page, preferably in <article> or <main>
+	Lead   string            `json:"lead"`
+	Image  *HeuristicImage   `json:"image"`
+	Videos []*HeuristicMedia `json:"videos"`
+	Audios []*HeuristicMedia `json:"audios"`
+	// Times are the datetime attributes of <time> elements, in document order
+	Times []string `json:"times"`
+}
+
+type bodyScanner struct {
+	base       *url.URL
+	heuristics *Heuristics
+}
+
+// parseBody fills result.Heuristics from the body of doc and reads the JSON-LD blocks the head
+// loop did not. Those it read are the first ones of the document, without a <body> tag
+// html.Parse puts some of them in the implied body, so they are skipped by count.
+func (result *Result) parseBody(doc *html.Node, jsonldBlocks int) {
+	var body *html.Node
+	walkElements(doc, func(n *html.Node) {
+		if n.DataAtom == atom.Body && body == nil {
+			body = n
+		}
+		if n.DataAtom == atom.Script && isJSONLDScript(attrValue(n, "type")) {
+			if jsonldBlocks > 0 {
+				jsonldBlocks--
+				return
+			}
+			result.parseJSONLD([]byte(textContent(n)))
+		}
+	})
+	if body == nil {
+		return
+	}
+
+	s := &bodyScanner{heuristics: &Heuristics{}}
+	if base, err := url.Parse(result.BaseURL); err == nil && base.IsAbs() {
+		s.base = base
+	}
+	walkContent(body, false, s.element)
+	s.heuristics.Lead = leadParagraph(body)
+
+	h := s.heuristics
+	if h.Heading != "" || h.Lead != "" || h.Image != nil || len(h.Videos) > 0 || len(h.Audios) > 0 || len(h.Times) > 0 {
+		result.Heuristics = h
+	}
+}
+
+func (s *bodyScanner) element(n *html.Node) {
+	h := s.heuristics
+	switch n.DataAtom {
+	case atom.H1:
+		if h.Heading == "" {
+			h.Heading = collapseSpace(textContent(n))
+		}
+	case atom.Img:
+		if h.Image == nil {
+			h.Image = s.image(n)
+		}
+	case atom.Video:
+		h.Videos = append(h.Videos, s.sources(n)...)
+	case atom.Audio:
+		h.Audios = append(h.Audios, s.sources(n)...)
+	case atom.Time:
+		if datetime := strings.TrimSpace(attrValue(n, "datetime")); datetime != "" {
+			h.Times = append(h.Times, datetime)
+		}
+	}
+}
+
+// image returns n if it declares a size large enough for a preview
+func (s *bodyScanner) image(n *html.Node) *HeuristicImage {
+	src := strings.TrimSpace(attrValue(n, "src"))
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return nil
+	}
+	width, height := pixels(attrValue(n, "width")), pixels(attrValue(n, "height"))
+	if width < minImageSize || height < minImageSize {
+		return nil
+	}
+	return &HeuristicImage{URL: resolveReference(s.base, src), Width: width, Height: height, Alt: attrValue(n, "alt")}
+}
+
+// sources returns the src of a media element and of its <source> children
+func (s *bodyScanner) sources(n *html.Node) []*HeuristicMedia {
+	var media []*HeuristicMedia
+	if src := strings.TrimSpace(attrValue(n, "src")); src != "" {
+		media = append(media, &HeuristicMedia{URL: resolveReference(s.base, src)})
+	}
+	for _, c := range appendChildElements(nil, n) {
+		if c.DataAtom != atom.Source {
+			continue
+		}
+		if src := strings.TrimSpace(attrValue(c, "src")); src != "" {
+			media = append(media, &HeuristicMedia{URL: resolveReference(s.base, src), Type: attrValue(c, "type")})
+		}
+	}
+	return media
+}
+
+// leadParagraph returns the first long enough paragraph of the first <article> or <main>,
+// or of the whole body when they have none
+func leadParagraph(body *html.Node) string {
+	var roots []*html.Node
+	walkElements(body, func(n *html.Node) {
+		if (n.DataAtom == atom.Article || n.DataAtom == atom.Main) && len(roots) == 0 {
+			roots = append(roots, n)
+		}
+	})
+	roots = append(roots, body)
+
+	for _, root := range roots {
+		lead := ""
+		walkContent(root, root.DataAtom != atom.Body, func(n *html.Node) {
+			if n.DataAtom != atom.P || lead != "" {
+				return
+			}
+			if text := collapseSpace(textContent(n)); utf8.RuneCountInString(text) >= minLeadLength {
+				lead = text
+			}
+		})
+		if lead != "" {
+			return lead
+		}
+	}
+	return ""
+}
+
+// walkContent calls fn on the elements of n in tree order, skipping hidden elements and
+// boilerplate such as navigation, asides, forms and the page header and footer
+func walkContent(n *html.Node, inArticle bool, fn func(*html.Node)) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.DataAtom {
+		case atom.Nav, atom.Aside, atom.Form, atom.Script, atom.Style, atom.Noscript, atom.Template:
+			continue
+		case atom.Header, atom.Footer:
+			// The header of an article holds its title, the one of the page holds the logo
+			if !inArticle {
+				continue
+			}
+		}
+		if hasAttr(c, "hidden") || attrValue(c, "aria-hidden") == "true" {
+			continue
+		}
+		fn(c)
+		walkContent(c, inArticle || c.DataAtom == atom.Article || c.DataAtom == atom.Main, fn)
+	}
+}
+
+func attrValue(n *html.Node, key string) string {
+	value, _ := getAttr(n, key)
+	return value
+}
+
+// pixels reads a width or height attribute such as 640 or 640px
+func pixels(value string) int64 {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
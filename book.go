@@ -8,15 +8,15 @@ type Book struct {
 	Authors     []string `json:"authors"`
 }
 
-func (p *Parser) parseBookMeta(attrs map[string]string) {
+func (result *Result) parseBookMeta(attrs map[string]string) {
 	switch attrs["property"] {
 	case "book:author":
-		p.Book.Authors = append(p.Book.Authors, attrs["content"])
+		result.Book.Authors = append(result.Book.Authors, attrs["content"])
 	case "book:isbn":
-		p.Book.Isbn = attrs["content"]
+		result.Book.Isbn = attrs["content"]
 	case "book:release_date":
-		p.Book.ReleaseDate = attrs["content"]
+		result.Book.ReleaseDate = attrs["content"]
 	case "book:tag":
-		p.Book.Tags = append(p.Book.Tags, attrs["content"])
+		result.Book.Tags = append(result.Book.Tags, attrs["content"])
 	}
 }
@@ -0,0 +1,41 @@
+package parser
+
+type contactData struct {
+	StreetAddress string `json:"street_address"`
+	Locality      string `json:"locality"`
+	Region        string `json:"region"`
+	PostalCode    string `json:"postal_code"`
+	CountryName   string `json:"country_name"`
+	Email         string `json:"email"`
+	PhoneNumber   string `json:"phone_number"`
+	FaxNumber     string `json:"fax_number"`
+	Website       string `json:"website"`
+}
+
+// Business type in Open Graph
+type Business struct {
+	ContactData contactData `json:"contact_data"`
+}
+
+func (result *Result) parseBusinessMeta(attrs map[string]string) {
+	switch attrs["property"] {
+	case "business:contact_data:street_address":
+		result.Business.ContactData.StreetAddress = attrs["content"]
+	case "business:contact_data:locality":
+		result.Business.ContactData.Locality = attrs["content"]
+	case "business:contact_data:region":
+		result.Business.ContactData.Region = attrs["content"]
+	case "business:contact_data:postal_code":
+		result.Business.ContactData.PostalCode = attrs["content"]
+	case "business:contact_data:country_name":
+		result.Business.ContactData.CountryName = attrs["content"]
+	case "business:contact_data:email":
+		result.Business.ContactData.Email = attrs["content"]
+	case "business:contact_data:phone_number":
+		result.Business.ContactData.PhoneNumber = attrs["content"]
+	case "business:contact_data:fax_number":
+		result.Business.ContactData.FaxNumber = attrs["content"]
+	case "business:contact_data:website":
+		result.Business.ContactData.Website = attrs["content"]
+	}
+}
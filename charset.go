@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// Number of bytes inspected for a <meta> charset declaration, as in the HTML spec
+	charsetPrescanBytes = 1024
+	defaultCharset      = "utf-8"
+)
+
+var byteOrderMarks = []struct {
+	bom     []byte
+	charset string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+}
+
+// decodeCharset detects the encoding of r and returns a reader producing UTF-8 along with
+// the name of the detected encoding. The byte order mark wins over the Content-Type header,
+// which wins over a <meta> declaration in the first 1024 bytes, UTF-8 is assumed otherwise.
+func decodeCharset(r io.Reader, contentType string) (io.Reader, string) {
+	br := bufio.NewReaderSize(r, charsetPrescanBytes)
+	head, _ := br.Peek(charsetPrescanBytes)
+
+	labels := []string{}
+	for _, mark := range byteOrderMarks {
+		if bytes.HasPrefix(head, mark.bom) {
+			labels = append(labels, mark.charset)
+			br.Discard(len(mark.bom))
+			break
+		}
+	}
+	labels = append(labels, contentTypeCharset(contentType), prescanCharset(head))
+
+	for _, label := range labels {
+		enc, name := charset.Lookup(label)
+		if enc == nil {
+			// Unknown or missing label, try the next source
+			continue
+		}
+		if name == defaultCharset {
+			break
+		}
+		return enc.NewDecoder().Reader(br), name
+	}
+
+	return br, defaultCharset
+}
+
+// contentTypeCharset returns the charset parameter of a Content-Type value
+func contentTypeCharset(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
+// prescanCharset looks for <meta charset> or <meta http-equiv="Content-Type"> in head
+func prescanCharset(head []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(head))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if atom.Lookup(name) != atom.Meta || !hasAttr {
+				continue
+			}
+			attrs := getAttributes(z)
+			label := attrs["charset"]
+			if label == "" && strings.EqualFold(attrs["http-equiv"], "content-type") {
+				label = contentTypeCharset(attrs["content"])
+			}
+			if label == "" {
+				continue
+			}
+			// A document that was decoded well enough to read this tag cannot be UTF-16
+			if strings.HasPrefix(strings.ToLower(label), "utf-16") {
+				return defaultCharset
+			}
+			return label
+		}
+	}
+}
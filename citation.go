@@ -0,0 +1,337 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CitationAuthor is a citation_author with the citation_author_* tags that follow it
+type CitationAuthor struct {
+	Name         string   `json:"name"`
+	Institutions []string `json:"institutions"`
+	Email        string   `json:"email"`
+	ORCID        string   `json:"orcid"`
+}
+
+// Citation holds the Highwire Press citation_* meta names read by Google Scholar
+type Citation struct {
+	Title           string            `json:"title"`
+	Authors         []*CitationAuthor `json:"authors"`
+	PublicationDate string            `json:"publication_date"`
+	OnlineDate      string            `json:"online_date"`
+	JournalTitle    string            `json:"journal_title"`
+	JournalAbbrev   string            `json:"journal_abbrev"`
+	ConferenceTitle string            `json:"conference_title"`
+	// Dissertation and technical report institutions
+	Dissertation    string   `json:"dissertation_institution"`
+	TechReport      string   `json:"technical_report_institution"`
+	TechReportNum   string   `json:"technical_report_number"`
+	Publisher       string   `json:"publisher"`
+	Volume          string   `json:"volume"`
+	Issue           string   `json:"issue"`
+	FirstPage       string   `json:"firstpage"`
+	LastPage        string   `json:"lastpage"`
+	DOI             string   `json:"doi"`
+	ISSN            string   `json:"issn"`
+	ISBN            string   `json:"isbn"`
+	PMID            string   `json:"pmid"`
+	ArxivID         string   `json:"arxiv_id"`
+	Language        string   `json:"language"`
+	Keywords        []string `json:"keywords"`
+	PDFURL          string   `json:"pdf_url"`
+	AbstractHTMLURL string   `json:"abstract_html_url"`
+	FulltextHTMLURL string   `json:"fulltext_html_url"`
+}
+
+func isCitationName(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "citation_")
+}
+
+func (result *Result) parseCitationMeta(attrs map[string]string) {
+	c := &result.Citation
+	content := strings.TrimSpace(attrs["content"])
+	if content == "" {
+		return
+	}
+	switch strings.ToLower(attrs["name"]) {
+	case "citation_title":
+		setOnce(&c.Title, content)
+	case "citation_author":
+		c.Authors = append(c.Authors, &CitationAuthor{Name: content})
+	case "citation_authors":
+		// Older pages list every author in one tag
+		for _, name := range strings.Split(content, ";") {
+			if name = strings.TrimSpace(name); name != "" {
+				c.Authors = append(c.Authors, &CitationAuthor{Name: name})
+			}
+		}
+	case "citation_author_institution":
+		if author := c.lastAuthor(); author != nil {
+			author.Institutions = append(author.Institutions, content)
+		}
+	case "citation_author_email":
+		if author := c.lastAuthor(); author != nil {
+			author.Email = content
+		}
+	case "citation_author_orcid":
+		if author := c.lastAuthor(); author != nil {
+			author.ORCID = content
+		}
+	case "citation_publication_date", "citation_date", "citation_cover_date":
+		setOnce(&c.PublicationDate, content)
+	case "citation_online_date":
+		setOnce(&c.OnlineDate, content)
+	case "citation_journal_title":
+		setOnce(&c.JournalTitle, content)
+	case "citation_journal_abbrev":
+		setOnce(&c.JournalAbbrev, content)
+	case "citation_conference_title", "citation_conference":
+		setOnce(&c.ConferenceTitle, content)
+	case "citation_dissertation_institution":
+		setOnce(&c.Dissertation, content)
+	case "citation_technical_report_institution":
+		setOnce(&c.TechReport, content)
+	case "citation_technical_report_number":
+		setOnce(&c.TechReportNum, content)
+	case "citation_publisher":
+		setOnce(&c.Publisher, content)
+	case "citation_volume":
+		setOnce(&c.Volume, content)
+	case "citation_issue":
+		setOnce(&c.Issue, content)
+	case "citation_firstpage":
+		setOnce(&c.FirstPage, content)
+	case "citation_lastpage":
+		setOnce(&c.LastPage, content)
+	case "citation_doi":
+		setOnce(&c.DOI, strings.TrimPrefix(strings.TrimPrefix(content, "doi:"), "https://doi.org/"))
+	case "citation_issn":
+		setOnce(&c.ISSN, content)
+	case "citation_isbn":
+		setOnce(&c.ISBN, content)
+	case "citation_pmid":
+		setOnce(&c.PMID, content)
+	case "citation_arxiv_id":
+		setOnce(&c.ArxivID, content)
+	case "citation_language":
+		setOnce(&c.Language, content)
+	case "citation_keywords":
+		for _, keyword := range strings.Split(content, ";") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				c.Keywords = append(c.Keywords, keyword)
+			}
+		}
+	case "citation_pdf_url":
+		setOnce(&c.PDFURL, content)
+	case "citation_abstract_html_url":
+		setOnce(&c.AbstractHTMLURL, content)
+	case "citation_fulltext_html_url":
+		setOnce(&c.FulltextHTMLURL, content)
+	}
+}
+
+func (c *Citation) lastAuthor() *CitationAuthor {
+	if len(c.Authors) == 0 {
+		return nil
+	}
+	return c.Authors[len(c.Authors)-1]
+}
+
+// url is the page of the work, the PDF as a last resort
+func (c *Citation) url() string {
+	return firstNonEmpty(c.AbstractHTMLURL, c.FulltextHTMLURL, c.PDFURL)
+}
+
+func (c *Citation) pages() string {
+	if c.FirstPage != "" && c.LastPage != "" {
+		return c.FirstPage + "-" + c.LastPage
+	}
+	return c.FirstPage
+}
+
+// splitName returns the family and given names of "Family, Given" or "Given Family"
+func splitName(name string) (family, given string) {
+	if i := strings.IndexByte(name, ','); i >= 0 {
+		return strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:])
+	}
+	if i := strings.LastIndexByte(name, ' '); i >= 0 {
+		return strings.TrimSpace(name[i+1:]), strings.TrimSpace(name[:i])
+	}
+	return name, ""
+}
+
+// dateParts reads dates such as 2020/05/17, 2020-05 or 2020
+func dateParts(date string) []int {
+	var parts []int
+	for _, field := range strings.FieldsFunc(date, func(r rune) bool { return r == '/' || r == '-' || r == ' ' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil || len(parts) == 3 {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// key builds a citation key such as doe2020
+func (c *Citation) key() string {
+	key := "citation"
+	if author := c.firstAuthor(); author != nil {
+		family, _ := splitName(author.Name)
+		key = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, family)
+	}
+	if parts := dateParts(c.PublicationDate); len(parts) > 0 {
+		key += strconv.Itoa(parts[0])
+	}
+	return key
+}
+
+func (c *Citation) firstAuthor() *CitationAuthor {
+	if len(c.Authors) == 0 {
+		return nil
+	}
+	return c.Authors[0]
+}
+
+// BibTeX exports the citation as a BibTeX entry, @article, @inproceedings, @phdthesis,
+// @techreport or @misc depending on where the work was published
+func (c *Citation) BibTeX() string {
+	entry, container := "misc", ""
+	switch {
+	case c.JournalTitle != "":
+		entry, container = "article", "journal"
+	case c.ConferenceTitle != "":
+		entry, container = "inproceedings", "booktitle"
+	case c.Dissertation != "":
+		entry = "phdthesis"
+	case c.TechReport != "":
+		entry = "techreport"
+	}
+
+	var authors []string
+	for _, author := range c.Authors {
+		authors = append(authors, author.Name)
+	}
+	year := ""
+	if parts := dateParts(c.PublicationDate); len(parts) > 0 {
+		year = strconv.Itoa(parts[0])
+	}
+
+	fields := [][2]string{
+		{"title", c.Title},
+		{"author", strings.Join(authors, " and ")},
+		{container, firstNonEmpty(c.JournalTitle, c.ConferenceTitle)},
+		{"school", c.Dissertation},
+		{"institution", c.TechReport},
+		{"number", firstNonEmpty(c.TechReportNum, c.Issue)},
+		{"year", year},
+		{"volume", c.Volume},
+		{"pages", strings.Replace(c.pages(), "-", "--", 1)},
+		{"publisher", c.Publisher},
+		{"doi", c.DOI},
+		{"issn", c.ISSN},
+		{"isbn", c.ISBN},
+		{"url", c.url()},
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "@%s{%s,\n", entry, c.key())
+	for _, field := range fields {
+		if field[0] != "" && field[1] != "" {
+			fmt.Fprintf(&b, "  %s = {%s},\n", field[0], bibtexEscape(field[1]))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+var bibtexReplacer = strings.NewReplacer(`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`)
+
+func bibtexEscape(value string) string {
+	return bibtexReplacer.Replace(value)
+}
+
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+type cslItem struct {
+	ID             string    `json:"id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title,omitempty"`
+	Author         []cslName `json:"author,omitempty"`
+	Issued         *cslDate  `json:"issued,omitempty"`
+	ContainerTitle string    `json:"container-title,omitempty"`
+	ContainerShort string    `json:"container-title-short,omitempty"`
+	Volume         string    `json:"volume,omitempty"`
+	Issue          string    `json:"issue,omitempty"`
+	Page           string    `json:"page,omitempty"`
+	Publisher      string    `json:"publisher,omitempty"`
+	Number         string    `json:"number,omitempty"`
+	DOI            string    `json:"DOI,omitempty"`
+	ISSN           string    `json:"ISSN,omitempty"`
+	ISBN           string    `json:"ISBN,omitempty"`
+	PMID           string    `json:"PMID,omitempty"`
+	URL            string    `json:"URL,omitempty"`
+	Language       string    `json:"language,omitempty"`
+	Keyword        string    `json:"keyword,omitempty"`
+}
+
+// CSLJSON exports the citation as a CSL-JSON item, as read by Zotero, Pandoc and citeproc
+func (c *Citation) CSLJSON() ([]byte, error) {
+	item := cslItem{
+		ID:             firstNonEmpty(c.DOI, c.key()),
+		Type:           "article",
+		Title:          c.Title,
+		ContainerTitle: firstNonEmpty(c.JournalTitle, c.ConferenceTitle),
+		ContainerShort: c.JournalAbbrev,
+		Volume:         c.Volume,
+		Issue:          c.Issue,
+		Page:           c.pages(),
+		Publisher:      firstNonEmpty(c.Publisher, c.Dissertation, c.TechReport),
+		Number:         c.TechReportNum,
+		DOI:            c.DOI,
+		ISSN:           c.ISSN,
+		ISBN:           c.ISBN,
+		PMID:           c.PMID,
+		URL:            c.url(),
+		Language:       c.Language,
+		Keyword:        strings.Join(c.Keywords, ", "),
+	}
+	switch {
+	case c.JournalTitle != "":
+		item.Type = "article-journal"
+	case c.ConferenceTitle != "":
+		item.Type = "paper-conference"
+	case c.Dissertation != "":
+		item.Type = "thesis"
+	case c.TechReport != "":
+		item.Type = "report"
+	}
+	for _, author := range c.Authors {
+		family, given := splitName(author.Name)
+		if given == "" {
+			item.Author = append(item.Author, cslName{Literal: author.Name})
+		} else {
+			item.Author = append(item.Author, cslName{Family: family, Given: given})
+		}
+	}
+	if parts := dateParts(c.PublicationDate); len(parts) > 0 {
+		item.Issued = &cslDate{DateParts: [][]int{parts}}
+	}
+	return json.Marshal(item)
+}
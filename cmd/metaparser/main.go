@@ -0,0 +1,255 @@
+// Command metaparser fetches pages or reads HTML files and prints the metadata found in them.
+//
+// Usage:
+//
+//	metaparser [flags] [url|file|-]...
+//
+// Without arguments the document is read from stdin.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	parser "github.com/ammit/go-metaparser"
+)
+
+const (
+	exitError   = 1
+	exitUsage   = 2
+	exitMissing = 3
+)
+
+// required maps the tags accepted by -require to a check on the result
+var required = map[string]func(r *parser.Result) bool{
+	"title":               func(r *parser.Result) bool { return r.Title != "" },
+	"description":         func(r *parser.Result) bool { return r.Description != "" },
+	"og:title":            func(r *parser.Result) bool { return r.OpenGraph.Title != "" },
+	"og:type":             func(r *parser.Result) bool { return r.OpenGraph.Type != "" },
+	"og:url":              func(r *parser.Result) bool { return r.OpenGraph.URL != "" },
+	"og:description":      func(r *parser.Result) bool { return r.OpenGraph.Description != "" },
+	"og:site_name":        func(r *parser.Result) bool { return r.OpenGraph.SiteName != "" },
+	"og:image":            func(r *parser.Result) bool { return len(r.Images) > 0 },
+	"og:video":            func(r *parser.Result) bool { return len(r.Videos) > 0 },
+	"og:audio":            func(r *parser.Result) bool { return len(r.Audios) > 0 },
+	"twitter:card":        func(r *parser.Result) bool { return r.Twitter.Card != "" },
+	"twitter:title":       func(r *parser.Result) bool { return r.Twitter.Title != "" },
+	"twitter:description": func(r *parser.Result) bool { return r.Twitter.Description != "" },
+	"twitter:image":       func(r *parser.Result) bool { return r.Twitter.Image != "" },
+	"twitter:site":        func(r *parser.Result) bool { return r.Twitter.Site != "" },
+	"favicon":             func(r *parser.Result) bool { return len(r.Favicons) > 0 },
+	"canonical":           func(r *parser.Result) bool { return r.Links.Canonical != "" },
+	"json-ld":             func(r *parser.Result) bool { return len(r.JSONLD.Blocks) > 0 },
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run is the whole command, it returns the exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("metaparser", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "json", "output format, json or table")
+	timeout := flags.Duration("timeout", 30*time.Second, "time allowed for each document")
+	userAgent := flags.String("user-agent", "", "User-Agent header sent when fetching")
+	only := flags.String("only", "", "comma separated result keys to print, e.g. open_graph,twitter")
+	require := flags.String("require", "", "comma separated tags that must be present, e.g. og:title,og:image")
+	preview := flags.Bool("preview", false, "print the flattened link preview instead of the full result")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: metaparser [flags] [url|file|-]...\n\nFlags:\n")
+		flags.PrintDefaults()
+		fmt.Fprintf(stderr, "\nTags accepted by -require: %s\n", strings.Join(requiredTags(), ", "))
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return exitUsage
+	}
+
+	if *format != "json" && *format != "table" {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return exitUsage
+	}
+	requiredList := splitList(*require)
+	for _, tag := range requiredList {
+		if _, ok := required[tag]; !ok {
+			fmt.Fprintf(stderr, "unknown required tag %q\n", tag)
+			return exitUsage
+		}
+	}
+
+	// The client timeout must not cut documents short of -timeout
+	opts := []parser.Option{parser.WithTimeout(*timeout)}
+	if *userAgent != "" {
+		opts = append(opts, parser.WithUserAgent(*userAgent))
+	}
+
+	inputs := flags.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	p := parser.New(opts...)
+	code := 0
+	for _, input := range inputs {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		result, err := parse(ctx, p, input, stdin)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", input, err)
+			code = exitError
+			continue
+		}
+
+		var value interface{} = result
+		if *preview {
+			value = result.Preview()
+		}
+		if err := output(stdout, input, value, *format, splitList(*only), len(inputs) > 1); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", input, err)
+			code = exitError
+			continue
+		}
+
+		for _, tag := range missingTags(result, requiredList) {
+			fmt.Fprintf(stderr, "%s: missing %s\n", input, tag)
+			if code == 0 {
+				code = exitMissing
+			}
+		}
+	}
+	return code
+}
+
+// missingTags returns the tags of list the result does not have
+func missingTags(result *parser.Result, list []string) []string {
+	var missing []string
+	for _, tag := range list {
+		if !required[tag](result) {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+// parse fetches URLs and reads files, "-" is stdin
+func parse(ctx context.Context, p *parser.Parser, input string, stdin io.Reader) (*parser.Result, error) {
+	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
+		return p.ParseURL(ctx, input)
+	}
+
+	if input == "-" {
+		return p.ParseReader(ctx, stdin)
+	}
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return p.ParseReader(ctx, f)
+}
+
+// output prints the keys of value selected by only, as indented JSON or as a table of dotted keys
+func output(w io.Writer, input string, value interface{}, format string, only []string, header bool) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	if len(only) > 0 {
+		selected := make(map[string]interface{})
+		for _, key := range only {
+			if v, ok := fields[key]; ok {
+				selected[key] = v
+			}
+		}
+		fields = selected
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(fields)
+	}
+
+	if header {
+		fmt.Fprintf(w, "==> %s <==\n", input)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	rows := make(map[string]string)
+	flatten(rows, "", fields)
+	keys := make([]string, 0, len(rows))
+	for key := range rows {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(tw, "%s\t%s\n", key, rows[key])
+	}
+	return tw.Flush()
+}
+
+// flatten turns nested JSON values into dotted keys, empty values are left out
+func flatten(rows map[string]string, prefix string, value interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			flatten(rows, join(key), item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flatten(rows, join(fmt.Sprint(i)), item)
+		}
+	case nil:
+	case string:
+		if v != "" {
+			rows[prefix] = v
+		}
+	case float64:
+		if v != 0 {
+			rows[prefix] = fmt.Sprint(v)
+		}
+	case bool:
+		if v {
+			rows[prefix] = "true"
+		}
+	}
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func requiredTags() []string {
+	tags := make([]string, 0, len(required))
+	for tag := range required {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const page = `
+<html>
+<head>
+	<title>Go Meta Parser</title>
+	<meta property="og:title" content="sample title" />
+	<meta property="og:image" content="https://example.com/a.jpg" />
+</head>
+`
+
+func runPage(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(page), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunJSON(t *testing.T) {
+	code, stdout, stderr := runPage("-only", "title,open_graph")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &fields); err != nil {
+		t.Fatalf("invalid json %q", stdout)
+	}
+	if len(fields) != 2 || fields["title"] != "Go Meta Parser" {
+		t.Errorf("-only selected the wrong keys: %v", fields)
+	}
+	if og, _ := fields["open_graph"].(map[string]interface{}); og == nil || og["title"] != "sample title" {
+		t.Errorf("open_graph printed incorrectly: %v", fields["open_graph"])
+	}
+}
+
+func TestRunTable(t *testing.T) {
+	code, stdout, stderr := runPage("-format", "table", "-only", "title,images")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("table printed incorrectly: %q", stdout)
+	}
+	if fields := strings.Fields(lines[0]); len(fields) != 2 || fields[0] != "images.0.url" || fields[1] != "https://example.com/a.jpg" {
+		t.Errorf("nested values must be flattened into dotted keys: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "title ") || !strings.HasSuffix(lines[1], "Go Meta Parser") {
+		t.Errorf("table rows must be sorted by key: %q", lines[1])
+	}
+}
+
+func TestRunRequire(t *testing.T) {
+	if code, _, stderr := runPage("-require", "title,og:title,og:image"); code != 0 {
+		t.Errorf("present tags reported missing: %d %s", code, stderr)
+	}
+
+	code, _, stderr := runPage("-require", "og:title,twitter:card,canonical")
+	if code != exitMissing {
+		t.Errorf("missing tags must exit with %d, got %d", exitMissing, code)
+	}
+	if !strings.Contains(stderr, "missing twitter:card") || !strings.Contains(stderr, "missing canonical") {
+		t.Errorf("missing tags not reported: %q", stderr)
+	}
+
+	if code, _, _ := runPage("-require", "og:nope"); code != exitUsage {
+		t.Errorf("unknown required tags must exit with %d, got %d", exitUsage, code)
+	}
+	if code, _, _ := runPage("-format", "yaml"); code != exitUsage {
+		t.Errorf("unknown formats must exit with %d, got %d", exitUsage, code)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	rows := make(map[string]string)
+	flatten(rows, "", map[string]interface{}{
+		"title":  "x",
+		"empty":  "",
+		"width":  float64(0),
+		"height": float64(630),
+		"locked": false,
+		"nested": map[string]interface{}{"list": []interface{}{"a", nil, true}},
+	})
+
+	want := map[string]string{"title": "x", "height": "630", "nested.list.0": "a", "nested.list.2": "true"}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("flattened incorrectly: %v", rows)
+	}
+}
@@ -0,0 +1,78 @@
+package parser
+
+import "strings"
+
+// DublinCore holds the DC.* and dcterms.* meta names, refinements such as
+// DC.date.issued or dcterms.created count as their element
+type DublinCore struct {
+	Title        string   `json:"title"`
+	Creators     []string `json:"creators"`
+	Subjects     []string `json:"subjects"`
+	Description  string   `json:"description"`
+	Publisher    string   `json:"publisher"`
+	Contributors []string `json:"contributors"`
+	Date         string   `json:"date"`
+	Type         string   `json:"type"`
+	Format       string   `json:"format"`
+	Identifiers  []string `json:"identifiers"`
+	Source       string   `json:"source"`
+	Language     string   `json:"language"`
+	Relation     string   `json:"relation"`
+	Coverage     string   `json:"coverage"`
+	Rights       string   `json:"rights"`
+}
+
+// dublinCoreElement returns the element of a DC.* or dcterms.* name, lower cased, or ""
+func dublinCoreElement(name string) string {
+	name = strings.ToLower(name)
+	for _, prefix := range []string{"dc.", "dcterms.", "dc:", "dcterms:"} {
+		if strings.HasPrefix(name, prefix) {
+			element := strings.TrimPrefix(name, prefix)
+			if i := strings.IndexByte(element, '.'); i > 0 {
+				element = element[:i]
+			}
+			return element
+		}
+	}
+	return ""
+}
+
+func (result *Result) parseDublinCoreMeta(attrs map[string]string) {
+	dc := &result.DublinCore
+	content := strings.TrimSpace(attrs["content"])
+	if content == "" {
+		return
+	}
+	switch dublinCoreElement(attrs["name"]) {
+	case "title":
+		setOnce(&dc.Title, content)
+	case "creator":
+		dc.Creators = append(dc.Creators, content)
+	case "subject":
+		dc.Subjects = append(dc.Subjects, content)
+	case "description", "abstract":
+		setOnce(&dc.Description, content)
+	case "publisher":
+		setOnce(&dc.Publisher, content)
+	case "contributor":
+		dc.Contributors = append(dc.Contributors, content)
+	case "date", "issued", "created", "available", "modified":
+		setOnce(&dc.Date, content)
+	case "type":
+		setOnce(&dc.Type, content)
+	case "format":
+		setOnce(&dc.Format, content)
+	case "identifier":
+		dc.Identifiers = append(dc.Identifiers, content)
+	case "source":
+		setOnce(&dc.Source, content)
+	case "language":
+		setOnce(&dc.Language, content)
+	case "relation":
+		setOnce(&dc.Relation, content)
+	case "coverage":
+		setOnce(&dc.Coverage, content)
+	case "rights", "license":
+		setOnce(&dc.Rights, content)
+	}
+}
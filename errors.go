@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNotHTML is returned when a page is served with a Content-Type that is not HTML
+	ErrNotHTML = errors.New("content is not html")
+	// ErrBodyTooLarge is returned when a body exceeds the size set by WithMaxBodySize
+	ErrBodyTooLarge = errors.New("body too large")
+	// ErrTooManyRedirects is returned when a fetch is redirected more than 10 times
+	ErrTooManyRedirects = errors.New("too many redirects")
+	// ErrNotFeed is returned by FetchFeed when the document is not RSS, Atom or JSON Feed
+	ErrNotFeed = errors.New("content is not a feed")
+)
+
+// HTTPStatusError is returned when a page is answered with a status outside of 2xx
+type HTTPStatusError struct {
+	StatusCode int
+	// URL is the final URL of the request, after redirects
+	URL string
+	// RetryAfter is the delay requested by the Retry-After header, zero when absent
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s returned %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func newHTTPStatusError(resp *http.Response) *HTTPStatusError {
+	return &HTTPStatusError{
+		StatusCode: resp.StatusCode,
+		URL:        resp.Request.URL.String(),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter accepts both forms of Retry-After, delay-seconds and HTTP-date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
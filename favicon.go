@@ -8,7 +8,7 @@ type Favicon struct {
 	Sizes string `json:"sizes"`
 }
 
-func (p *Parser) parseFaviconLink(attrs map[string]string) {
+func (result *Result) parseFaviconLink(attrs map[string]string) {
 	favicon := &Favicon{
 		Name: attrs["rel"],
 	}
@@ -25,5 +25,5 @@ func (p *Parser) parseFaviconLink(attrs map[string]string) {
 		favicon.Sizes = val
 	}
 
-	p.Favicons = append(p.Favicons, favicon)
+	result.Favicons = append(result.Favicons, favicon)
 }
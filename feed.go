@@ -0,0 +1,326 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// FeedLink is a feed advertised with <link rel="alternate" type="application/rss+xml">
+type FeedLink struct {
+	URL   string `json:"url"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+}
+
+// Feed is the channel-level metadata of an RSS, Atom or JSON feed
+type Feed struct {
+	// Format is "rss", "atom" or "json"
+	Format      string `json:"format"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Link is the website the feed belongs to
+	Link     string  `json:"link"`
+	Image    string  `json:"image"`
+	Language string  `json:"language"`
+	Author   string  `json:"author"`
+	Updated  string  `json:"updated"`
+	ITunes   *ITunes `json:"itunes,omitempty"`
+}
+
+// ITunes holds the iTunes podcast tags of an RSS channel
+type ITunes struct {
+	Author     string   `json:"author"`
+	Subtitle   string   `json:"subtitle"`
+	Summary    string   `json:"summary"`
+	Image      string   `json:"image"`
+	Categories []string `json:"categories"`
+	Explicit   string   `json:"explicit"`
+	Type       string   `json:"type"`
+	OwnerName  string   `json:"owner_name"`
+	OwnerEmail string   `json:"owner_email"`
+}
+
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+func isFeedType(linkType string) bool {
+	return feedTypes[strings.ToLower(strings.TrimSpace(linkType))]
+}
+
+func (result *Result) parseFeedLink(attrs map[string]string) {
+	result.Feeds = append(result.Feeds, &FeedLink{
+		URL:   strings.TrimSpace(attrs["href"]),
+		Type:  strings.ToLower(strings.TrimSpace(attrs["type"])),
+		Title: attrs["title"],
+	})
+}
+
+// FetchFeed fetches the feed at target and returns its channel metadata, relative URLs
+// are resolved against the URL the feed was served from
+func (p *Parser) FetchFeed(ctx context.Context, target string) (*Feed, error) {
+	resp, err := p.get(ctx, strings.TrimSpace(target))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+
+	body, err := ioutil.ReadAll(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed *Feed
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		feed, err = parseJSONFeed(body)
+	} else {
+		feed, err = parseXMLFeed(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	feed.Link = resolveReference(resp.url, feed.Link)
+	feed.Image = resolveReference(resp.url, feed.Image)
+	if feed.ITunes != nil {
+		feed.ITunes.Image = resolveReference(resp.url, feed.ITunes.Image)
+	}
+	return feed, nil
+}
+
+// rss10Namespace is the default namespace of RSS 1.0 documents, RSS 2.0 has none
+const rss10Namespace = "http://purl.org/rss/1.0/"
+
+type rssDocument struct {
+	Channel rssChannel `xml:"channel"`
+	// RSS 1.0 puts the image next to the channel
+	Image struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+}
+
+// A tag without a namespace matches the element in any namespace, so <title>, <description> and
+// <link> are kept with their names and only those of RSS itself are used. The iTunes fields
+// come first as encoding/xml gives an element to the first field matching it.
+type rssChannel struct {
+	ITunesAuthor   string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	ITunesSubtitle string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd subtitle"`
+	ITunesSummary  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+	ITunesImage    struct {
+		Href string `xml:"href,attr"`
+	} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ITunesCategories []itunesCategory `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd category"`
+	ITunesExplicit   string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+	ITunesType       string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd type"`
+	ITunesOwner      struct {
+		Name  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd name"`
+		Email string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd email"`
+	} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd owner"`
+
+	Titles       []rssElement `xml:"title"`
+	Descriptions []rssElement `xml:"description"`
+	// <link> and <atom:link rel="self">, only the former is the website
+	Links []rssElement `xml:"link"`
+	Image struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+	Language       string `xml:"language"`
+	ManagingEditor string `xml:"managingEditor"`
+	LastBuildDate  string `xml:"lastBuildDate"`
+	PubDate        string `xml:"pubDate"`
+}
+
+type itunesCategory struct {
+	Text          string           `xml:"text,attr"`
+	Subcategories []itunesCategory `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd category"`
+}
+
+type atomFeed struct {
+	Title    string `xml:"title"`
+	Subtitle string `xml:"subtitle"`
+	Links    []struct {
+		Rel  string `xml:"rel,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"link"`
+	Logo    string `xml:"logo"`
+	Icon    string `xml:"icon"`
+	Updated string `xml:"updated"`
+	Authors []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+}
+
+type jsonFeed struct {
+	Version     string `json:"version"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	HomePageURL string `json:"home_page_url"`
+	Icon        string `json:"icon"`
+	Favicon     string `json:"favicon"`
+	Language    string `json:"language"`
+	Author      struct {
+		Name string `json:"name"`
+	} `json:"author"`
+	Authors []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+}
+
+func parseXMLFeed(body []byte) (*Feed, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+
+	// Skip the prolog up to the root element
+	var root xml.StartElement
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, ErrNotFeed
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root = start
+			break
+		}
+	}
+
+	switch root.Name.Local {
+	case "rss", "RDF":
+		doc := &rssDocument{}
+		if err := decoder.DecodeElement(doc, &root); err != nil {
+			return nil, err
+		}
+		feed := doc.Channel.feed()
+		if feed.Image == "" {
+			feed.Image = strings.TrimSpace(doc.Image.URL)
+		}
+		return feed, nil
+	case "feed":
+		doc := &atomFeed{}
+		if err := decoder.DecodeElement(doc, &root); err != nil {
+			return nil, err
+		}
+		return doc.feed(), nil
+	}
+	return nil, fmt.Errorf("%w: <%s>", ErrNotFeed, root.Name.Local)
+}
+
+type rssElement struct {
+	XMLName xml.Name
+	Text    string `xml:",chardata"`
+}
+
+// rssText returns the first non-empty element of RSS 2.0, without a namespace, or of RSS 1.0
+func rssText(elements []rssElement) string {
+	for _, element := range elements {
+		space := element.XMLName.Space
+		if (space == "" || space == rss10Namespace) && strings.TrimSpace(element.Text) != "" {
+			return strings.TrimSpace(element.Text)
+		}
+	}
+	return ""
+}
+
+func (channel *rssChannel) feed() *Feed {
+	feed := &Feed{
+		Format:      "rss",
+		Title:       rssText(channel.Titles),
+		Description: rssText(channel.Descriptions),
+		Link:        rssText(channel.Links),
+		Image:       strings.TrimSpace(channel.Image.URL),
+		Language:    strings.TrimSpace(channel.Language),
+		Author:      strings.TrimSpace(channel.ManagingEditor),
+		Updated:     strings.TrimSpace(firstNonEmpty(channel.LastBuildDate, channel.PubDate)),
+	}
+	itunes := &ITunes{
+		Author:     strings.TrimSpace(channel.ITunesAuthor),
+		Subtitle:   strings.TrimSpace(channel.ITunesSubtitle),
+		Summary:    strings.TrimSpace(channel.ITunesSummary),
+		Image:      strings.TrimSpace(channel.ITunesImage.Href),
+		Explicit:   strings.TrimSpace(channel.ITunesExplicit),
+		Type:       strings.TrimSpace(channel.ITunesType),
+		OwnerName:  strings.TrimSpace(channel.ITunesOwner.Name),
+		OwnerEmail: strings.TrimSpace(channel.ITunesOwner.Email),
+	}
+	itunes.Categories = appendITunesCategories(nil, channel.ITunesCategories)
+	if len(itunes.Categories) > 0 || itunes.Author+itunes.Subtitle+itunes.Summary+itunes.Image+
+		itunes.Explicit+itunes.Type+itunes.OwnerName+itunes.OwnerEmail != "" {
+		feed.ITunes = itunes
+		if feed.Image == "" {
+			feed.Image = itunes.Image
+		}
+		if feed.Author == "" {
+			feed.Author = itunes.Author
+		}
+	}
+	return feed
+}
+
+// appendITunesCategories flattens categories, a subcategory follows its parent
+func appendITunesCategories(categories []string, nested []itunesCategory) []string {
+	for _, category := range nested {
+		if text := strings.TrimSpace(category.Text); text != "" {
+			categories = append(categories, text)
+		}
+		categories = appendITunesCategories(categories, category.Subcategories)
+	}
+	return categories
+}
+
+func (doc *atomFeed) feed() *Feed {
+	feed := &Feed{
+		Format:      "atom",
+		Title:       strings.TrimSpace(doc.Title),
+		Description: strings.TrimSpace(doc.Subtitle),
+		Image:       strings.TrimSpace(firstNonEmpty(doc.Logo, doc.Icon)),
+		Updated:     strings.TrimSpace(doc.Updated),
+	}
+	for _, link := range doc.Links {
+		// A link without rel is the alternate
+		if link.Rel == "" || link.Rel == "alternate" {
+			feed.Link = strings.TrimSpace(link.Href)
+			break
+		}
+	}
+	if len(doc.Authors) > 0 {
+		feed.Author = strings.TrimSpace(doc.Authors[0].Name)
+	}
+	return feed
+}
+
+func parseJSONFeed(body []byte) (*Feed, error) {
+	doc := &jsonFeed{}
+	if err := json.Unmarshal(body, doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("%w: json without a JSON Feed version", ErrNotFeed)
+	}
+
+	feed := &Feed{
+		Format:      "json",
+		Title:       doc.Title,
+		Description: doc.Description,
+		Link:        doc.HomePageURL,
+		Image:       firstNonEmpty(doc.Icon, doc.Favicon),
+		Language:    doc.Language,
+		Author:      doc.Author.Name,
+	}
+	if len(doc.Authors) > 0 {
+		feed.Author = doc.Authors[0].Name
+	}
+	return feed, nil
+}
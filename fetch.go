@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	httpClientTimeoutSeconds = 30
+	maxRedirects             = 10
+)
+
+// FetchHTML returns buffer
+func (p *Parser) FetchHTML(target string) (io.ReadCloser, error) {
+	return p.FetchHTMLContext(context.Background(), target)
+}
+
+// FetchHTMLContext returns buffer, the request and every read from the body are bound to ctx
+func (p *Parser) FetchHTMLContext(ctx context.Context, target string) (io.ReadCloser, error) {
+	target = strings.TrimSpace(target)
+
+	return p.fetch(ctx, target)
+}
+
+// buildClient assembles the http.Client described by the parser options
+func (p *Parser) buildClient() *http.Client {
+	client := &http.Client{
+		Timeout: time.Second * httpClientTimeoutSeconds,
+	}
+	if p.client != nil {
+		client = p.client
+	}
+	if p.transport != nil {
+		client.Transport = p.transport
+	}
+	if p.timeout > 0 {
+		client.Timeout = p.timeout
+	}
+	if client.CheckRedirect == nil {
+		client.CheckRedirect = checkRedirect
+	}
+	// The proxy is set first so that the safe dialer wraps it
+	if p.proxy != nil {
+		transport := client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		if t, ok := transport.(*http.Transport); ok {
+			t = t.Clone()
+			t.Proxy = p.proxy
+			client.Transport = t
+		}
+	}
+	if p.safeDialer != nil {
+		transport := client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		if t, ok := transport.(*http.Transport); ok {
+			client.Transport = newSafeTransport(p.safeDialer, t)
+		} else {
+			// Addresses cannot be checked below another RoundTripper, fetching nothing is safer than fetching unchecked
+			client.Transport = refusingTransport{}
+		}
+		next := client.CheckRedirect
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if err := p.safeDialer.checkURL(req.URL); err != nil {
+				return err
+			}
+			return next(req, via)
+		}
+	}
+
+	return client
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return ErrTooManyRedirects
+	}
+	return nil
+}
+
+func (p *Parser) httpClient() *http.Client {
+	if p.client != nil {
+		return p.client
+	}
+	// Parser was not created by New
+	return p.buildClient()
+}
+
+func (p *Parser) fetch(ctx context.Context, target string) (io.ReadCloser, error) {
+	resp, err := p.get(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isHTML(resp.contentType) {
+		resp.Close()
+		return nil, fmt.Errorf("%w: %s", ErrNotHTML, resp.contentType)
+	}
+	return resp, nil
+}
+
+// get requests target with the parser's client and headers, whatever the content type of the answer
+func (p *Parser) get(ctx context.Context, target string) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.safeDialer != nil {
+		if err := p.safeDialer.checkURL(req.URL); err != nil {
+			return nil, err
+		}
+	}
+	for key, values := range p.header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+
+	resp, err := p.httpClient().Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		resp.Body.Close()
+		return nil, newHTTPStatusError(resp)
+	}
+
+	body := resp.Body
+	if p.maxBodySize > 0 {
+		body = &maxBytesReader{ReadCloser: body, remaining: p.maxBodySize}
+	}
+
+	return &response{
+		contextReader: contextReader{ctx: ctx, ReadCloser: body},
+		contentType:   resp.Header.Get("Content-Type"),
+		url:           resp.Request.URL,
+	}, nil
+}
+
+// isHTML reports whether contentType may hold an HTML document,
+// a missing or broken header is given the benefit of the doubt
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// response is the body returned by get, it remembers what the headers said about it
+type response struct {
+	contextReader
+	contentType string
+	url         *url.URL
+}
+
+// maxBytesReader fails with ErrBodyTooLarge once more than remaining bytes are available
+type maxBytesReader struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (r *maxBytesReader) Read(b []byte) (int, error) {
+	if r.remaining <= 0 {
+		// Only an error if the body really goes on
+		var probe [1]byte
+		n, err := r.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, ErrBodyTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(b)) > r.remaining {
+		b = b[:r.remaining]
+	}
+	n, err := r.ReadCloser.Read(b)
+	r.remaining -= int64(n)
+	return n, err
+}
+
+// contextReader stops reading as soon as its context is done
+type contextReader struct {
+	io.ReadCloser
+	ctx context.Context
+}
+
+func (r *contextReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.ReadCloser.Read(b)
+	if err != nil && r.ctx.Err() != nil {
+		// The transport reports cancellation in its own words, surface the context error instead
+		return n, r.ctx.Err()
+	}
+	return n, err
+}
@@ -0,0 +1,608 @@
+package parser_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	parser "github.com/ammit/go-metaparser"
+)
+
+func TestParserParseURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(html))
+	}))
+	defer ts.Close()
+
+	p := parser.New()
+	result, err := p.ParseURL(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.GetTitle() != "sample title" {
+		t.Error("ParseURL does not return correct title")
+	}
+}
+
+func TestParserParseURLDeadline(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>slow</title>"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	p := parser.New()
+	result, err := p.ParseURL(ctx, ts.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if result != nil {
+		t.Error("a cancelled parse must not return a partial result")
+	}
+}
+
+func TestParserParseHTMLContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := parser.New()
+	err := p.ParseHTMLContext(ctx, ioutil.NopCloser(strings.NewReader(html)))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestParserOptions(t *testing.T) {
+	var got *http.Request
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+			Body:       ioutil.NopCloser(strings.NewReader(titleHtml)),
+			Request:    r,
+		}, nil
+	})
+
+	p := parser.New(
+		parser.WithTransport(transport),
+		parser.WithUserAgent("metaparser-test"),
+		parser.WithAcceptLanguage("de-DE"),
+		parser.WithHeader("X-Trace", "abc"),
+		parser.WithTimeout(time.Second),
+	)
+	result, err := p.ParseURL(context.Background(), "http://example.invalid/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Title != "Go Meta Parser" {
+		t.Error("title parsed incorrectly through custom transport")
+	}
+
+	if got.Header.Get("User-Agent") != "metaparser-test" {
+		t.Error("User-Agent header not sent")
+	}
+
+	if got.Header.Get("Accept-Language") != "de-DE" {
+		t.Error("Accept-Language header not sent")
+	}
+
+	if got.Header.Get("X-Trace") != "abc" {
+		t.Error("extra header not sent")
+	}
+}
+
+func TestParserParseURLContentTypeCharset(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=windows-1251")
+		w.Write([]byte("<html><head><title>\xcf\xf0\xe8\xe2\xe5\xf2</title></head>"))
+	}))
+	defer ts.Close()
+
+	result, err := parser.New().ParseURL(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Title != "Привет" {
+		t.Errorf("title decoded incorrectly: %q", result.Title)
+	}
+
+	if result.Charset != "windows-1251" {
+		t.Errorf("charset detected incorrectly: %q", result.Charset)
+	}
+}
+
+func TestParserFetchErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/busy", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>" + strings.Repeat("a", 4096) + "</title></head>"))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	p := parser.New(parser.WithMaxBodySize(1024))
+
+	var statusErr *parser.HTTPStatusError
+	_, err := p.ParseURL(context.Background(), ts.URL+"/missing")
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404 HTTPStatusError, got %v", err)
+	}
+
+	_, err = p.ParseURL(context.Background(), ts.URL+"/busy")
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected a 503 HTTPStatusError, got %v", err)
+	} else {
+		if statusErr.RetryAfter != 120*time.Second {
+			t.Errorf("Retry-After parsed incorrectly: %v", statusErr.RetryAfter)
+		}
+		if statusErr.URL != ts.URL+"/busy" {
+			t.Errorf("final URL reported incorrectly: %v", statusErr.URL)
+		}
+	}
+
+	_, err = p.ParseURL(context.Background(), ts.URL+"/image")
+	if !errors.Is(err, parser.ErrNotHTML) {
+		t.Errorf("expected ErrNotHTML, got %v", err)
+	}
+
+	_, err = p.ParseURL(context.Background(), ts.URL+"/loop")
+	if !errors.Is(err, parser.ErrTooManyRedirects) {
+		t.Errorf("expected ErrTooManyRedirects, got %v", err)
+	}
+
+	b, err := p.FetchHTML(ts.URL + "/large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if _, err = ioutil.ReadAll(b); !errors.Is(err, parser.ErrBodyTooLarge) {
+		t.Errorf("expected ErrBodyTooLarge, got %v", err)
+	}
+}
+
+func TestParserSafeDialer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(titleHtml))
+	}))
+	defer ts.Close()
+
+	p := parser.New(parser.WithSafeDialer())
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(ts.URL, "http://"))
+	allowed := parser.New(parser.WithSafeDialer(80, 443, mustAtoi(t, port)))
+
+	tests := []struct {
+		parser *parser.Parser
+		url    string
+		reason string
+	}{
+		{p, "http://169.254.169.254/latest/meta-data/", "link-local"},
+		{p, "http://10.0.0.1/", "private"},
+		{p, "http://[::ffff:127.0.0.1]/", "loopback"},
+		{p, "http://[fd00::1]/", "private"},
+		{p, "http://[64:ff9b::c0a8:1]/", "private"},
+		{p, "http://224.0.0.1/", "multicast"},
+		{p, "http://example.com:8080/", "port 8080 not allowed"},
+		{p, "ftp://example.com/", `scheme "ftp" not allowed`},
+		{p, ts.URL, "port " + port + " not allowed"},
+		{allowed, ts.URL, "loopback"},
+	}
+
+	for _, test := range tests {
+		_, err := test.parser.ParseURL(context.Background(), test.url)
+		var blocked *parser.BlockedError
+		if !errors.As(err, &blocked) {
+			t.Errorf("%s: expected a BlockedError, got %v", test.url, err)
+			continue
+		}
+		if blocked.Reason != test.reason {
+			t.Errorf("%s: blocked for %q instead of %q", test.url, blocked.Reason, test.reason)
+		}
+	}
+}
+
+func mustAtoi(t *testing.T, s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestParserParseURLBudget(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>slow</title>"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	p := parser.New(parser.WithMaxParseTime(50 * time.Millisecond))
+	result, err := p.ParseURL(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Truncated || result.Title != "slow" {
+		t.Errorf("expected a truncated result with the title, got %+v", result)
+	}
+}
+
+func TestParserParseURLOEmbed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+			<link rel="alternate" type="text/xml+oembed" href="/oembed.xml">
+			<link rel="alternate" type="application/json+oembed" href="/oembed.json" title="Post">
+		</head></html>`))
+	})
+	mux.HandleFunc("/oembed.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type":"video","version":"1.0","title":"Post","html":"<iframe></iframe>","width":"640","height":360}`))
+	})
+	mux.HandleFunc("/video/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Video</title></head></html>`))
+	})
+	mux.HandleFunc("/api/oembed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<oembed><type>photo</type><version>1.0</version><url>` + r.URL.Query().Get("url") + `.jpg</url><width>800</width><height>600</height></oembed>`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	p := parser.New(parser.WithOEmbed(), parser.WithOEmbedProviders(parser.OEmbedProvider{
+		Name:     "Local",
+		Endpoint: ts.URL + "/api/oembed",
+		Schemes:  []string{ts.URL + "/video/*"},
+	}))
+
+	result, err := p.ParseURL(context.Background(), ts.URL+"/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.OEmbedLinks) != 2 || result.OEmbedLinks[1].URL != ts.URL+"/oembed.json" {
+		t.Fatalf("oEmbed links discovered incorrectly: %+v", result.OEmbedLinks)
+	}
+	if result.OEmbed == nil || result.OEmbed.Type != "video" || result.OEmbed.Width != 640 || result.OEmbed.Height != 360 {
+		t.Errorf("JSON oEmbed decoded incorrectly: %+v", result.OEmbed)
+	}
+
+	result, err = p.ParseURL(context.Background(), ts.URL+"/video/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OEmbed == nil || result.OEmbed.Type != "photo" || result.OEmbed.URL != ts.URL+"/video/42.jpg" || result.OEmbed.Width != 800 {
+		t.Errorf("XML oEmbed from the provider registry decoded incorrectly: %+v", result.OEmbed)
+	}
+}
+
+func TestParserFetchFeed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+			<link rel="alternate" type="application/rss+xml" title="Podcast" href="/podcast.rss">
+			<link rel="alternate" type="application/atom+xml" href="/atom.xml">
+			<link rel="alternate" type="application/feed+json" href="/feed.json">
+			<link rel="alternate" hreflang="de" href="/de/">
+		</head></html>`))
+	})
+	mux.HandleFunc("/podcast.rss", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:googleplay="http://www.google.com/schemas/play-podcasts/1.0">
+<channel>
+	<title>Caf` + "\xe9" + ` Gophers</title>
+	<atom:link href="https://example.com/podcast.rss" rel="self" type="application/rss+xml"/>
+	<link>/</link>
+	<description>Weekly Go talk</description>
+	<itunes:title>Gophers on iTunes</itunes:title>
+	<googleplay:description>Gophers on Google Play</googleplay:description>
+	<language>en</language>
+	<image><url>/cover.png</url></image>
+	<itunes:author>The Gophers</itunes:author>
+	<itunes:image href="/itunes.png"/>
+	<itunes:category text="Technology"><itunes:category text="Podcasting"/></itunes:category>
+	<itunes:explicit>false</itunes:explicit>
+	<itunes:owner><itunes:name>Jane</itunes:name><itunes:email>jane@example.com</itunes:email></itunes:owner>
+</channel>
+</rss>`))
+	})
+	mux.HandleFunc("/rdf.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rdf+xml")
+		w.Write([]byte(`<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel rdf:about="https://example.com/rdf.xml">
+	<title>RDF blog</title>
+	<link>/rdf/</link>
+	<description>Still on RSS 1.0</description>
+	<dc:language>en</dc:language>
+	<image rdf:resource="https://example.com/logo.png"/>
+</channel>
+<image rdf:about="https://example.com/logo.png"><title>RDF blog</title><url>/logo.png</url><link>/rdf/</link></image>
+</rdf:RDF>`))
+	})
+	mux.HandleFunc("/atom.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Atom blog</title>
+			<link rel="self" href="/atom.xml"/><link href="/blog/"/><author><name>Jane</name></author></feed>`))
+	})
+	mux.HandleFunc("/feed.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json")
+		w.Write([]byte(`{"version":"https://jsonfeed.org/version/1.1","title":"JSON blog","home_page_url":"https://example.com/","authors":[{"name":"Jane"}]}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	p := parser.New()
+	result, err := p.ParseURL(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Feeds) != 3 || result.Feeds[0].URL != ts.URL+"/podcast.rss" || result.Feeds[0].Title != "Podcast" {
+		t.Fatalf("feeds discovered incorrectly: %+v", result.Feeds)
+	}
+
+	rss, err := p.FetchFeed(context.Background(), result.Feeds[0].URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rss.Format != "rss" || rss.Title != "Café Gophers" || rss.Description != "Weekly Go talk" || rss.Link != ts.URL+"/" || rss.Image != ts.URL+"/cover.png" {
+		t.Errorf("rss channel parsed incorrectly: %+v", rss)
+	}
+	if rss.ITunes == nil || rss.ITunes.Author != "The Gophers" || rss.ITunes.Image != ts.URL+"/itunes.png" ||
+		len(rss.ITunes.Categories) != 2 || rss.ITunes.OwnerEmail != "jane@example.com" {
+		t.Errorf("itunes tags parsed incorrectly: %+v", rss.ITunes)
+	}
+
+	rdf, err := p.FetchFeed(context.Background(), ts.URL+"/rdf.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rdf.Format != "rss" || rdf.Title != "RDF blog" || rdf.Description != "Still on RSS 1.0" || rdf.Link != ts.URL+"/rdf/" ||
+		rdf.Image != ts.URL+"/logo.png" || rdf.Language != "en" {
+		t.Errorf("rss 1.0 channel parsed incorrectly: %+v", rdf)
+	}
+
+	atom, err := p.FetchFeed(context.Background(), result.Feeds[1].URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atom.Format != "atom" || atom.Title != "Atom blog" || atom.Link != ts.URL+"/blog/" || atom.Author != "Jane" {
+		t.Errorf("atom feed parsed incorrectly: %+v", atom)
+	}
+
+	jsonFeed, err := p.FetchFeed(context.Background(), result.Feeds[2].URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsonFeed.Format != "json" || jsonFeed.Title != "JSON blog" || jsonFeed.Author != "Jane" {
+		t.Errorf("json feed parsed incorrectly: %+v", jsonFeed)
+	}
+
+	if _, err := p.FetchFeed(context.Background(), ts.URL); !errors.Is(err, parser.ErrNotFeed) {
+		t.Errorf("expected ErrNotFeed for an html page, got %v", err)
+	}
+}
+
+func TestParserParseURLManifest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head>
+			<link rel="icon" href="/favicon.ico" sizes="32x32">
+			<link rel="manifest" href="/app/site.webmanifest">
+		</head></html>`))
+	})
+	mux.HandleFunc("/app/site.webmanifest", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/manifest+json")
+		w.Write([]byte(`{
+			"name": "Gopher App", "short_name": "Gopher", "start_url": "./?source=pwa", "display": "standalone",
+			"theme_color": "#00add8", "background_color": "#ffffff",
+			"icons": [
+				{"src": "icon-192.png", "sizes": "192x192", "type": "image/png"},
+				{"src": "/favicon.ico", "sizes": "32x32"},
+				{"src": "icon-512.png", "sizes": "512x512", "type": "image/png", "purpose": "any maskable"}
+			]
+		}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	result, err := parser.New().ParseURL(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Links.Manifest != ts.URL+"/app/site.webmanifest" || result.Manifest != nil {
+		t.Error("manifest must only be fetched with WithManifest")
+	}
+
+	result, err = parser.New(parser.WithManifest()).ParseURL(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := result.Manifest
+	if manifest == nil || manifest.Name != "Gopher App" || manifest.ShortName != "Gopher" || manifest.Display != "standalone" ||
+		manifest.ThemeColor != "#00add8" || manifest.StartURL != ts.URL+"/app/?source=pwa" {
+		t.Fatalf("manifest decoded incorrectly: %+v", manifest)
+	}
+	if len(manifest.Icons) != 3 || manifest.Icons[2].Purpose != "any maskable" || manifest.Icons[0].Src != ts.URL+"/app/icon-192.png" {
+		t.Error("manifest icons decoded incorrectly")
+	}
+	if len(result.Favicons) != 3 {
+		t.Errorf("manifest icons merged incorrectly: %d favicons", len(result.Favicons))
+	}
+	if result.Preview().Icon != ts.URL+"/app/icon-512.png" {
+		t.Error("preview must use the largest manifest icon")
+	}
+}
+
+func TestParserSafeDialerProxy(t *testing.T) {
+	var proxied []string
+	var mu sync.Mutex
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(titleHtml))
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	// The proxy itself is on loopback, as internal egress proxies are, and is still dialed
+	p := parser.New(parser.WithProxy(proxyURL), parser.WithSafeDialer())
+	result, err := p.ParseURL(context.Background(), "http://93.184.215.14/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Title != "Go Meta Parser" {
+		t.Error("page fetched through the proxy parsed incorrectly")
+	}
+
+	// The target behind the proxy is checked, redirects included
+	for _, target := range []string{"http://169.254.169.254/latest/", "http://localhost/", "http://93.184.215.14:8080/", "http://93.184.215.14/redirect"} {
+		_, err = p.ParseURL(context.Background(), target)
+		var blocked *parser.BlockedError
+		if !errors.As(err, &blocked) {
+			t.Errorf("%s: expected a BlockedError through the proxy, got %v", target, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(proxied) != 2 {
+		t.Errorf("only the public targets may reach the proxy: %v", proxied)
+	}
+}
+
+func TestParserSafeDialerTunnel(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(titleHtml))
+	}))
+	defer target.Close()
+
+	// Tunnels every CONNECT to the local TLS server, whatever was asked
+	var tunnels []string
+	var mu sync.Mutex
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		mu.Lock()
+		tunnels = append(tunnels, r.Host)
+		mu.Unlock()
+		upstream, err := net.Dial("tcp", target.Listener.Addr().String())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	p := parser.New(parser.WithTransport(transport), parser.WithProxy(proxyURL), parser.WithSafeDialer())
+	result, err := p.ParseURL(context.Background(), "https://93.184.215.14/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Title != "Go Meta Parser" {
+		t.Error("page fetched through the tunnel parsed incorrectly")
+	}
+
+	_, err = p.ParseURL(context.Background(), "https://169.254.169.254/")
+	var blocked *parser.BlockedError
+	if !errors.As(err, &blocked) {
+		t.Errorf("expected a BlockedError through the tunnel, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The proxy is given the checked address, it never resolves the target itself
+	if len(tunnels) != 1 || tunnels[0] != "93.184.215.14:443" {
+		t.Errorf("tunnels opened incorrectly: %v", tunnels)
+	}
+}
+
+func TestParserSafeDialerCustomTransport(t *testing.T) {
+	called := false
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		called = true
+		return nil, errors.New("must not be called")
+	})
+
+	p := parser.New(parser.WithTransport(transport), parser.WithSafeDialer())
+	_, err := p.ParseURL(context.Background(), "http://127.0.0.1/")
+	var blocked *parser.BlockedError
+	if !errors.As(err, &blocked) || called {
+		t.Errorf("a transport that cannot be checked must refuse every fetch, got %v", err)
+	}
+}
@@ -14,44 +14,44 @@ type Image struct {
 	Alt       string `json:"alt"`
 }
 
-func (p *Parser) ensureImage() {
-	if len(p.Images) > 0 {
+func (result *Result) ensureImage() {
+	if len(result.Images) > 0 {
 		return
 	}
-	p.Images = append(p.Images, &Image{})
+	result.Images = append(result.Images, &Image{})
 }
 
-func (p *Parser) parseImageMeta(attrs map[string]string) {
+func (result *Result) parseImageMeta(attrs map[string]string) {
 	switch attrs["property"] {
 	case "og:image":
-		if len(p.Images) > 0 && len(p.Images[len(p.Images)-1].URL) == 0 {
-			p.Images[len(p.Images)-1].URL = attrs["content"]
+		if len(result.Images) > 0 && len(result.Images[len(result.Images)-1].URL) == 0 {
+			result.Images[len(result.Images)-1].URL = attrs["content"]
 		} else {
-			p.Images = append(p.Images, &Image{URL: attrs["content"]})
+			result.Images = append(result.Images, &Image{URL: attrs["content"]})
 		}
 	case "og:image:url":
-		p.ensureImage()
-		p.Images[len(p.Images)-1].URL = attrs["content"]
+		result.ensureImage()
+		result.Images[len(result.Images)-1].URL = attrs["content"]
 	case "og:image:secure_url":
-		p.ensureImage()
-		p.Images[len(p.Images)-1].SecureURL = attrs["content"]
+		result.ensureImage()
+		result.Images[len(result.Images)-1].SecureURL = attrs["content"]
 	case "og:image:type":
-		p.ensureImage()
-		p.Images[len(p.Images)-1].Type = attrs["content"]
+		result.ensureImage()
+		result.Images[len(result.Images)-1].Type = attrs["content"]
 	case "og:image:width":
 		w, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.ensureImage()
-			p.Images[len(p.Images)-1].Width = w
+			result.ensureImage()
+			result.Images[len(result.Images)-1].Width = w
 		}
 	case "og:image:height":
 		h, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.ensureImage()
-			p.Images[len(p.Images)-1].Height = h
+			result.ensureImage()
+			result.Images[len(result.Images)-1].Height = h
 		}
 	case "og:image:alt":
-		p.ensureImage()
-		p.Images[len(p.Images)-1].Alt = attrs["content"]
+		result.ensureImage()
+		result.Images[len(result.Images)-1].Alt = attrs["content"]
 	}
 }
@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// JSONLD holds the structured data of the <script type="application/ld+json"> blocks
+type JSONLD struct {
+	// Blocks are the scripts as written, one entry per valid block
+	Blocks []json.RawMessage `json:"blocks"`
+	// Nodes are the objects described by the blocks, top-level arrays and @graph are flattened
+	Nodes []map[string]interface{} `json:"-"`
+}
+
+func isJSONLDScript(scriptType string) bool {
+	mediaType, _, err := mime.ParseMediaType(scriptType)
+	return err == nil && mediaType == "application/ld+json"
+}
+
+func (result *Result) parseJSONLD(data []byte) {
+	data = bytes.TrimSpace(data)
+	var block interface{}
+	if err := json.Unmarshal(data, &block); err != nil {
+		return
+	}
+
+	result.JSONLD.Blocks = append(result.JSONLD.Blocks, json.RawMessage(data))
+	result.JSONLD.Nodes = appendJSONLDNodes(result.JSONLD.Nodes, block)
+}
+
+func appendJSONLDNodes(nodes []map[string]interface{}, value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			nodes = appendJSONLDNodes(nodes, item)
+		}
+	case map[string]interface{}:
+		graph, hasGraph := v["@graph"]
+		if !hasGraph || v["@type"] != nil {
+			nodes = append(nodes, v)
+		}
+		if hasGraph {
+			nodes = appendJSONLDNodes(nodes, graph)
+		}
+	}
+	return nodes
+}
+
+// NodesOfType returns the nodes whose @type is one of types, matched without vocabulary prefix
+func (ld *JSONLD) NodesOfType(types ...string) []map[string]interface{} {
+	var nodes []map[string]interface{}
+	for _, node := range ld.Nodes {
+		if hasSchemaType(node, types) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func hasSchemaType(node map[string]interface{}, types []string) bool {
+	for _, nodeType := range ldTexts(node, "@type") {
+		nodeType = schemaTypeName(nodeType)
+		for _, t := range types {
+			if nodeType == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schemaTypeName strips "http://schema.org/" or "schema:" from a type
+func schemaTypeName(t string) string {
+	if i := strings.LastIndexAny(t, "/:#"); i >= 0 {
+		return t[i+1:]
+	}
+	return t
+}
+
+// ldText returns the value of key as a string, taking the first one of arrays
+// and the name or @value of objects
+func ldText(node map[string]interface{}, key string) string {
+	texts := ldTexts(node, key)
+	if len(texts) == 0 {
+		return ""
+	}
+	return texts[0]
+}
+
+func ldTexts(node map[string]interface{}, key string) []string {
+	var texts []string
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			texts = append(texts, strings.TrimSpace(v))
+		case float64:
+			texts = append(texts, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			texts = append(texts, strconv.FormatBool(v))
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		case map[string]interface{}:
+			if value, ok := v["@value"]; ok {
+				collect(value)
+			} else if name, ok := v["name"]; ok {
+				collect(name)
+			} else if text, ok := v["text"]; ok {
+				collect(text)
+			}
+		}
+	}
+	collect(node[key])
+	return texts
+}
+
+// ldURLs returns the URLs of key, which may be strings, ImageObject-like objects or arrays of both
+func ldURLs(node map[string]interface{}, key string) []string {
+	var urls []string
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			urls = append(urls, strings.TrimSpace(v))
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		case map[string]interface{}:
+			for _, key := range []string{"url", "contentUrl", "@id"} {
+				if u, ok := v[key].(string); ok {
+					urls = append(urls, strings.TrimSpace(u))
+					return
+				}
+			}
+		}
+	}
+	collect(node[key])
+	return urls
+}
+
+func ldURL(node map[string]interface{}, key string) string {
+	urls := ldURLs(node, key)
+	if len(urls) == 0 {
+		return ""
+	}
+	return urls[0]
+}
+
+// ldObjects returns the objects of key, plain strings are turned into objects with a name
+func ldObjects(node map[string]interface{}, key string) []map[string]interface{} {
+	var objects []map[string]interface{}
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			objects = append(objects, map[string]interface{}{"name": v})
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		case map[string]interface{}:
+			objects = append(objects, v)
+		}
+	}
+	collect(node[key])
+	return objects
+}
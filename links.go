@@ -0,0 +1,82 @@
+package parser
+
+import "strings"
+
+// Alternate is a <link rel="alternate">, another language, media or format of the page
+type Alternate struct {
+	URL      string `json:"url"`
+	Hreflang string `json:"hreflang"`
+	Media    string `json:"media"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+}
+
+// Links holds the <link> tags relating the page to other URLs
+type Links struct {
+	Canonical  string       `json:"canonical"`
+	AMP        string       `json:"amp"`
+	Shortlink  string       `json:"shortlink"`
+	Prev       string       `json:"prev"`
+	Next       string       `json:"next"`
+	Manifest   string       `json:"manifest"`
+	Alternates []*Alternate `json:"alternates"`
+}
+
+// Hreflang maps every language of the alternates, x-default included, to its URL.
+// Languages are lower cased, the first link of a language wins.
+func (links *Links) Hreflang() map[string]string {
+	languages := make(map[string]string)
+	for _, alternate := range links.Alternates {
+		lang := strings.ToLower(strings.TrimSpace(alternate.Hreflang))
+		if lang == "" || alternate.URL == "" {
+			continue
+		}
+		if _, ok := languages[lang]; !ok {
+			languages[lang] = alternate.URL
+		}
+	}
+	return languages
+}
+
+func (result *Result) parseRelLink(attrs map[string]string) {
+	href := strings.TrimSpace(attrs["href"])
+	if href == "" {
+		return
+	}
+	for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+		switch rel {
+		case "canonical":
+			setOnce(&result.Links.Canonical, href)
+		case "amphtml":
+			setOnce(&result.Links.AMP, href)
+		case "shortlink":
+			setOnce(&result.Links.Shortlink, href)
+		case "prev", "previous":
+			setOnce(&result.Links.Prev, href)
+		case "next":
+			setOnce(&result.Links.Next, href)
+		case "manifest":
+			setOnce(&result.Links.Manifest, href)
+		case "alternate":
+			result.Links.Alternates = append(result.Links.Alternates, &Alternate{
+				URL:      href,
+				Hreflang: attrs["hreflang"],
+				Media:    attrs["media"],
+				Type:     attrs["type"],
+				Title:    attrs["title"],
+			})
+			if isFeedType(attrs["type"]) {
+				result.parseFeedLink(attrs)
+			} else if strings.HasPrefix(strings.ToLower(href), "android-app:") {
+				result.parseAndroidAppLink(href)
+			}
+		}
+	}
+}
+
+// setOnce keeps the first value, as browsers do for repeated <link> tags
+func setOnce(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
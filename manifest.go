@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// ManifestIcon is an icon of a Web App Manifest
+type ManifestIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+	// Purpose is "any", "maskable", "monochrome" or several of them
+	Purpose string `json:"purpose"`
+}
+
+// Manifest is the Web App Manifest linked with <link rel="manifest">
+type Manifest struct {
+	Name            string          `json:"name"`
+	ShortName       string          `json:"short_name"`
+	Description     string          `json:"description"`
+	StartURL        string          `json:"start_url"`
+	Scope           string          `json:"scope"`
+	Display         string          `json:"display"`
+	ThemeColor      string          `json:"theme_color"`
+	BackgroundColor string          `json:"background_color"`
+	Icons           []*ManifestIcon `json:"icons"`
+}
+
+// FetchManifest fetches and decodes the manifest at target, icon and start URLs
+// are resolved against the URL the manifest was served from
+func (p *Parser) FetchManifest(ctx context.Context, target string) (*Manifest, error) {
+	resp, err := p.get(ctx, strings.TrimSpace(target))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+
+	body, err := ioutil.ReadAll(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(body, manifest); err != nil {
+		return nil, err
+	}
+	manifest.StartURL = resolveReference(resp.url, manifest.StartURL)
+	manifest.Scope = resolveReference(resp.url, manifest.Scope)
+	for _, icon := range manifest.Icons {
+		icon.Src = resolveReference(resp.url, icon.Src)
+	}
+	return manifest, nil
+}
+
+// resolveManifest fills result.Manifest and adds its icons to the favicons,
+// a missing or broken manifest is not an error
+func (p *Parser) resolveManifest(ctx, budget context.Context, result *Result) error {
+	if u, err := url.Parse(result.Links.Manifest); err != nil || !u.IsAbs() {
+		return nil
+	}
+	manifest, err := p.FetchManifest(budget, result.Links.Manifest)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if budget.Err() != nil {
+		result.Truncated = true
+	}
+	if err == nil {
+		result.Manifest = manifest
+		result.mergeManifestIcons(manifest)
+	}
+	return nil
+}
+
+// mergeManifestIcons appends the manifest icons that are not favicons already
+func (result *Result) mergeManifestIcons(manifest *Manifest) {
+	known := make(map[string]bool)
+	for _, favicon := range result.Favicons {
+		known[favicon.URL] = true
+	}
+	for _, icon := range manifest.Icons {
+		if icon.Src == "" || known[icon.Src] {
+			continue
+		}
+		known[icon.Src] = true
+		result.Favicons = append(result.Favicons, &Favicon{
+			Name:  "manifest",
+			URL:   icon.Src,
+			Type:  icon.Type,
+			Sizes: icon.Sizes,
+		})
+	}
+}
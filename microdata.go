@@ -0,0 +1,246 @@
+package parser
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// MicrodataItem is an element with itemscope, its properties follow the WHATWG microdata model
+type MicrodataItem struct {
+	Types      []string                     `json:"type,omitempty"`
+	ID         string                       `json:"id,omitempty"`
+	Properties map[string][]*MicrodataValue `json:"properties"`
+}
+
+// MicrodataValue is the value of an itemprop, either text or a nested item
+type MicrodataValue struct {
+	Text string
+	Item *MicrodataItem
+}
+
+// MarshalJSON writes the value as in the microdata JSON format, a string or an item
+func (v *MicrodataValue) MarshalJSON() ([]byte, error) {
+	if v.Item != nil {
+		return json.Marshal(v.Item)
+	}
+	return json.Marshal(v.Text)
+}
+
+// Text returns the first text value of the property name
+func (item *MicrodataItem) Text(name string) string {
+	for _, value := range item.Properties[name] {
+		if value.Item == nil {
+			return value.Text
+		}
+	}
+	return ""
+}
+
+// Items returns the nested items of the property name
+func (item *MicrodataItem) Items(name string) []*MicrodataItem {
+	var items []*MicrodataItem
+	for _, value := range item.Properties[name] {
+		if value.Item != nil {
+			items = append(items, value.Item)
+		}
+	}
+	return items
+}
+
+// HasType reports whether the item is of type t, matched with or without vocabulary
+func (item *MicrodataItem) HasType(t string) bool {
+	for _, itemType := range item.Types {
+		if itemType == t || schemaTypeName(itemType) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// microdataParser runs the microdata algorithm over a parsed document
+type microdataParser struct {
+	base  *url.URL
+	ids   map[string]*html.Node
+	order map[*html.Node]int
+	// Items being built, to stop on itemref cycles
+	building map[*html.Node]bool
+}
+
+// parseMicrodata collects the top-level items of doc: elements with itemscope and without itemprop
+func (result *Result) parseMicrodata(doc *html.Node) {
+	m := &microdataParser{
+		ids:      make(map[string]*html.Node),
+		order:    make(map[*html.Node]int),
+		building: make(map[*html.Node]bool),
+	}
+	if base, err := url.Parse(result.BaseURL); err == nil && base.IsAbs() {
+		m.base = base
+	}
+
+	var topLevel []*html.Node
+	walkElements(doc, func(n *html.Node) {
+		m.order[n] = len(m.order)
+		if id, ok := getAttr(n, "id"); ok {
+			if _, seen := m.ids[id]; !seen {
+				m.ids[id] = n
+			}
+		}
+		if hasAttr(n, "itemscope") && !hasAttr(n, "itemprop") {
+			topLevel = append(topLevel, n)
+		}
+	})
+
+	for _, n := range topLevel {
+		result.Microdata = append(result.Microdata, m.item(n))
+	}
+}
+
+func (m *microdataParser) item(root *html.Node) *MicrodataItem {
+	m.building[root] = true
+	defer delete(m.building, root)
+
+	item := &MicrodataItem{
+		Properties: make(map[string][]*MicrodataValue),
+	}
+	if itemType, ok := getAttr(root, "itemtype"); ok {
+		item.Types = strings.Fields(itemType)
+	}
+	if id, ok := getAttr(root, "itemid"); ok && len(item.Types) > 0 {
+		item.ID = resolveReference(m.base, id)
+	}
+
+	for _, property := range m.properties(root) {
+		var value *MicrodataValue
+		if hasAttr(property, "itemscope") {
+			if m.building[property] {
+				// A cycle through itemref, the spec leaves the value out
+				continue
+			}
+			value = &MicrodataValue{Item: m.item(property)}
+		} else {
+			value = &MicrodataValue{Text: m.value(property)}
+		}
+		itemprop, _ := getAttr(property, "itemprop")
+		for _, name := range strings.Fields(itemprop) {
+			item.Properties[name] = append(item.Properties[name], value)
+		}
+	}
+	return item
+}
+
+// properties crawls the children of root and the elements it references with itemref,
+// without entering nested items, and returns the elements with itemprop in tree order
+func (m *microdataParser) properties(root *html.Node) []*html.Node {
+	var properties, pending []*html.Node
+	memory := map[*html.Node]bool{root: true}
+	pending = appendChildElements(pending, root)
+	if itemref, ok := getAttr(root, "itemref"); ok {
+		for _, id := range strings.Fields(itemref) {
+			if n, ok := m.ids[id]; ok {
+				pending = append(pending, n)
+			}
+		}
+	}
+
+	for len(pending) > 0 {
+		candidate := pending[0]
+		pending = pending[1:]
+		if memory[candidate] {
+			continue
+		}
+		memory[candidate] = true
+		if !hasAttr(candidate, "itemscope") {
+			pending = appendChildElements(pending, candidate)
+		}
+		if itemprop, ok := getAttr(candidate, "itemprop"); ok && strings.TrimSpace(itemprop) != "" {
+			properties = append(properties, candidate)
+		}
+	}
+
+	sort.SliceStable(properties, func(i, j int) bool {
+		return m.order[properties[i]] < m.order[properties[j]]
+	})
+	return properties
+}
+
+// value returns the property value of n as defined for each element type
+func (m *microdataParser) value(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.Meta:
+		content, _ := getAttr(n, "content")
+		return content
+	case atom.Audio, atom.Embed, atom.Iframe, atom.Img, atom.Source, atom.Track, atom.Video:
+		return m.resolveAttr(n, "src")
+	case atom.A, atom.Area, atom.Link:
+		return m.resolveAttr(n, "href")
+	case atom.Object:
+		return m.resolveAttr(n, "data")
+	case atom.Data, atom.Meter:
+		value, _ := getAttr(n, "value")
+		return value
+	case atom.Time:
+		if datetime, ok := getAttr(n, "datetime"); ok {
+			return datetime
+		}
+	}
+	return textContent(n)
+}
+
+func (m *microdataParser) resolveAttr(n *html.Node, key string) string {
+	value, _ := getAttr(n, key)
+	return resolveReference(m.base, value)
+}
+
+// walkElements calls fn for every element below n in tree order
+func walkElements(n *html.Node, fn func(*html.Node)) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			fn(c)
+		}
+		walkElements(c, fn)
+	}
+}
+
+func appendChildElements(nodes []*html.Node, n *html.Node) []*html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func getAttr(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	_, ok := getAttr(n, key)
+	return ok
+}
+
+// textContent concatenates the text below n
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.TrimSpace(b.String())
+}
@@ -27,50 +27,50 @@ type Music struct {
 	Songs       []*song  `json:"songs"`
 }
 
-func (p *Parser) ensureSongs() {
-	if len(p.Music.Songs) > 0 {
+func (result *Result) ensureSongs() {
+	if len(result.Music.Songs) > 0 {
 		return
 	}
-	p.Music.Songs = append(p.Music.Songs, &song{})
+	result.Music.Songs = append(result.Music.Songs, &song{})
 }
 
-func (p *Parser) parseMusicMeta(attrs map[string]string) {
+func (result *Result) parseMusicMeta(attrs map[string]string) {
 	switch attrs["property"] {
 	case "music:musician":
-		p.Music.Musicians = append(p.Music.Musicians, attrs["content"])
+		result.Music.Musicians = append(result.Music.Musicians, attrs["content"])
 	case "music:album":
-		p.Music.Album.URL = attrs["content"]
+		result.Music.Album.URL = attrs["content"]
 	case "music:duration":
 		t, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.Music.Duration = t
+			result.Music.Duration = t
 		}
 	case "music:release_date":
-		p.Music.ReleaseDate = attrs["content"]
+		result.Music.ReleaseDate = attrs["content"]
 	case "music:creator":
-		p.Music.Creator = attrs["content"]
+		result.Music.Creator = attrs["content"]
 	case "music:album:track":
 		t, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.Music.Album.Track = t
+			result.Music.Album.Track = t
 		}
 	case "music:song":
-		if len(p.Music.Songs) > 0 && len(p.Music.Songs[len(p.Music.Songs)-1].URL) == 0 {
-			p.Music.Songs[len(p.Music.Songs)-1].URL = attrs["content"]
+		if len(result.Music.Songs) > 0 && len(result.Music.Songs[len(result.Music.Songs)-1].URL) == 0 {
+			result.Music.Songs[len(result.Music.Songs)-1].URL = attrs["content"]
 		} else {
-			p.Music.Songs = append(p.Music.Songs, &song{URL: attrs["content"]})
+			result.Music.Songs = append(result.Music.Songs, &song{URL: attrs["content"]})
 		}
 	case "music:song:disc":
-		p.ensureSongs()
+		result.ensureSongs()
 		t, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.Music.Songs[len(p.Music.Songs)-1].Disc = t
+			result.Music.Songs[len(result.Music.Songs)-1].Disc = t
 		}
 	case "music:song:track":
-		p.ensureAudio()
+		result.ensureAudio()
 		t, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.Music.Songs[len(p.Music.Songs)-1].Track = t
+			result.Music.Songs[len(result.Music.Songs)-1].Track = t
 		}
 	}
 }
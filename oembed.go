@@ -0,0 +1,234 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// OEmbedLink is an oEmbed endpoint advertised with <link rel="alternate" type="application/json+oembed">
+type OEmbedLink struct {
+	URL   string `json:"url"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+}
+
+// OEmbed is an oEmbed response, fields are named as in https://oembed.com
+type OEmbed struct {
+	Type            string    `json:"type" xml:"type"`
+	Version         string    `json:"version" xml:"version"`
+	Title           string    `json:"title,omitempty" xml:"title"`
+	AuthorName      string    `json:"author_name,omitempty" xml:"author_name"`
+	AuthorURL       string    `json:"author_url,omitempty" xml:"author_url"`
+	ProviderName    string    `json:"provider_name,omitempty" xml:"provider_name"`
+	ProviderURL     string    `json:"provider_url,omitempty" xml:"provider_url"`
+	CacheAge        OEmbedInt `json:"cache_age,omitempty" xml:"cache_age"`
+	ThumbnailURL    string    `json:"thumbnail_url,omitempty" xml:"thumbnail_url"`
+	ThumbnailWidth  OEmbedInt `json:"thumbnail_width,omitempty" xml:"thumbnail_width"`
+	ThumbnailHeight OEmbedInt `json:"thumbnail_height,omitempty" xml:"thumbnail_height"`
+	// URL is the source of a photo
+	URL string `json:"url,omitempty" xml:"url"`
+	// HTML is the markup embedding a video or rich content
+	HTML   string    `json:"html,omitempty" xml:"html"`
+	Width  OEmbedInt `json:"width,omitempty" xml:"width"`
+	Height OEmbedInt `json:"height,omitempty" xml:"height"`
+}
+
+// OEmbedInt is an integer that providers send as a number or a string, anything else reads as 0
+type OEmbedInt int64
+
+// UnmarshalJSON accepts 640, "640" and null
+func (i *OEmbedInt) UnmarshalJSON(data []byte) error {
+	*i = 0
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*i = OEmbedInt(v)
+	case string:
+		*i = parseOEmbedInt(v)
+	}
+	return nil
+}
+
+// UnmarshalXML reads the text of the element
+func (i *OEmbedInt) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	*i = parseOEmbedInt(s)
+	return nil
+}
+
+func parseOEmbedInt(s string) OEmbedInt {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return OEmbedInt(f)
+}
+
+// OEmbedProvider is an oEmbed endpoint used for pages that do not advertise one.
+// Schemes are URL patterns where * matches anything, as in the oembed.com provider list.
+type OEmbedProvider struct {
+	Name     string
+	Endpoint string
+	Schemes  []string
+}
+
+// DefaultOEmbedProviders is the registry used unless WithOEmbedProviders is set
+var DefaultOEmbedProviders = []OEmbedProvider{
+	{
+		Name:     "YouTube",
+		Endpoint: "https://www.youtube.com/oembed",
+		Schemes:  []string{"https://*.youtube.com/watch*", "https://*.youtube.com/v/*", "https://*.youtube.com/shorts/*", "https://youtu.be/*"},
+	},
+	{
+		Name:     "Vimeo",
+		Endpoint: "https://vimeo.com/api/oembed.json",
+		Schemes:  []string{"https://vimeo.com/*", "https://player.vimeo.com/video/*"},
+	},
+	{
+		Name:     "SoundCloud",
+		Endpoint: "https://soundcloud.com/oembed",
+		Schemes:  []string{"https://soundcloud.com/*", "https://*.soundcloud.com/*"},
+	},
+	{
+		Name:     "Flickr",
+		Endpoint: "https://www.flickr.com/services/oembed/",
+		Schemes:  []string{"https://*.flickr.com/photos/*", "https://flic.kr/p/*"},
+	},
+}
+
+// Match reports whether the page at target is served by the provider, http and https alike
+func (provider OEmbedProvider) Match(target string) bool {
+	if strings.HasPrefix(target, "http://") {
+		target = "https://" + strings.TrimPrefix(target, "http://")
+	}
+	for _, scheme := range provider.Schemes {
+		if strings.HasPrefix(scheme, "http://") {
+			scheme = "https://" + strings.TrimPrefix(scheme, "http://")
+		}
+		// A leading *. in the host also matches the bare domain
+		pattern := regexp.QuoteMeta(scheme)
+		pattern = strings.Replace(pattern, `://\*\.`, `://([^/?#@]*\.)?`, 1)
+		pattern = strings.Replace(pattern, `\*`, `.*`, -1)
+		if matched, _ := regexp.MatchString("^"+pattern+"$", target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// EndpointURL returns the request for the oEmbed of the page at target
+func (provider OEmbedProvider) EndpointURL(target string) (string, error) {
+	endpoint, err := url.Parse(provider.Endpoint)
+	if err != nil {
+		return "", err
+	}
+	query := endpoint.Query()
+	query.Set("url", target)
+	query.Set("format", "json")
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String(), nil
+}
+
+func isOEmbedType(linkType string) bool {
+	linkType = strings.ToLower(strings.TrimSpace(linkType))
+	return linkType == "application/json+oembed" || linkType == "text/xml+oembed"
+}
+
+func (result *Result) parseOEmbedLink(attrs map[string]string) {
+	result.OEmbedLinks = append(result.OEmbedLinks, &OEmbedLink{
+		URL:   attrs["href"],
+		Type:  strings.ToLower(strings.TrimSpace(attrs["type"])),
+		Title: attrs["title"],
+	})
+}
+
+// oembedEndpoint returns the endpoint advertised by the page, JSON first,
+// or the one of the first provider matching target
+func (p *Parser) oembedEndpoint(result *Result, target string) string {
+	for _, link := range result.OEmbedLinks {
+		if link.Type == "application/json+oembed" && link.URL != "" {
+			return link.URL
+		}
+	}
+	for _, link := range result.OEmbedLinks {
+		if link.URL != "" {
+			return link.URL
+		}
+	}
+
+	providers := p.oembedProviders
+	if providers == nil {
+		providers = DefaultOEmbedProviders
+	}
+	for _, provider := range providers {
+		if provider.Match(target) {
+			if endpoint, err := provider.EndpointURL(target); err == nil {
+				return endpoint
+			}
+		}
+	}
+	return ""
+}
+
+// resolveOEmbed fills result.OEmbed, a page without a working endpoint is not an error
+func (p *Parser) resolveOEmbed(ctx, budget context.Context, result *Result, documentURL string) error {
+	endpoint := p.oembedEndpoint(result, documentURL)
+	if endpoint == "" {
+		return nil
+	}
+	oembed, err := p.FetchOEmbed(budget, endpoint)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if budget.Err() != nil {
+		result.Truncated = true
+	}
+	if err == nil {
+		result.OEmbed = oembed
+	}
+	return nil
+}
+
+// FetchOEmbed fetches and decodes the JSON or XML oEmbed response at endpoint
+func (p *Parser) FetchOEmbed(ctx context.Context, endpoint string) (*OEmbed, error) {
+	resp, err := p.get(ctx, strings.TrimSpace(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+
+	body, err := ioutil.ReadAll(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	oembed := &OEmbed{}
+	mediaType, _, _ := mime.ParseMediaType(resp.contentType)
+	if strings.HasSuffix(mediaType, "xml") || bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+		decoder := xml.NewDecoder(bytes.NewReader(body))
+		decoder.CharsetReader = charset.NewReaderLabel
+		err = decoder.Decode(oembed)
+	} else {
+		err = json.Unmarshal(body, oembed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return oembed, nil
+}
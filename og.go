@@ -11,24 +11,24 @@ type OG struct {
 	SiteName         string   `json:"site_name"`
 }
 
-func (p *Parser) parseBasicOGMeta(attrs map[string]string) {
+func (result *Result) parseBasicOGMeta(attrs map[string]string) {
 	switch attrs["property"] {
 	case "og:title":
-		p.OpenGraph.Title = attrs["content"]
+		result.OpenGraph.Title = attrs["content"]
 	case "og:type":
-		p.OpenGraph.Type = attrs["content"]
+		result.OpenGraph.Type = attrs["content"]
 	case "og:url":
-		p.OpenGraph.URL = attrs["content"]
+		result.OpenGraph.URL = attrs["content"]
 	case "og:description":
-		p.OpenGraph.Description = attrs["content"]
+		result.OpenGraph.Description = attrs["content"]
 	case "og:determiner":
-		p.OpenGraph.Determiner = attrs["content"]
+		result.OpenGraph.Determiner = attrs["content"]
 	case "og:locale":
-		p.OpenGraph.Locale = attrs["content"]
+		result.OpenGraph.Locale = attrs["content"]
 	case "og:locale:alternate":
-		p.OpenGraph.LocalesAlternate = append(p.OpenGraph.LocalesAlternate, attrs["content"])
+		result.OpenGraph.LocalesAlternate = append(result.OpenGraph.LocalesAlternate, attrs["content"])
 	case "og:site_name":
-		p.OpenGraph.SiteName = attrs["content"]
+		result.OpenGraph.SiteName = attrs["content"]
 
 	}
 }
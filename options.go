@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Option configures a Parser
+type Option func(*Parser)
+
+// WithHTTPClient makes the parser fetch pages with client instead of building its own.
+// The client is copied, so later options never modify the caller's value.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Parser) {
+		if client != nil {
+			c := *client
+			p.client = &c
+		}
+	}
+}
+
+// WithTransport sets the round tripper used for fetching, e.g. an httptest transport
+func WithTransport(transport http.RoundTripper) Option {
+	return func(p *Parser) {
+		p.transport = transport
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(p *Parser) {
+		p.header.Set("User-Agent", userAgent)
+	}
+}
+
+// WithAcceptLanguage sets the Accept-Language header sent with every request
+func WithAcceptLanguage(language string) Option {
+	return func(p *Parser) {
+		p.header.Set("Accept-Language", language)
+	}
+}
+
+// WithHeader adds an extra header sent with every request
+func WithHeader(key, value string) Option {
+	return func(p *Parser) {
+		p.header.Add(key, value)
+	}
+}
+
+// WithProxy routes every request through proxy.
+// It only applies when the transport in use is an *http.Transport.
+func WithProxy(proxy *url.URL) Option {
+	return func(p *Parser) {
+		p.proxy = http.ProxyURL(proxy)
+	}
+}
+
+// WithTimeout limits the time spent on a single fetch, the default is 30 seconds
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Parser) {
+		p.timeout = timeout
+	}
+}
+
+// WithMaxBodySize limits the bytes read from a document. Parsing stops there and the
+// result is marked Truncated, reads from a body returned by FetchHTML fail with ErrBodyTooLarge.
+func WithMaxBodySize(size int64) Option {
+	return func(p *Parser) {
+		p.maxBodySize = size
+	}
+}
+
+// WithBaseURL sets the address of documents that were not fetched by the parser,
+// relative URLs are resolved against it. Fetched documents use their final URL.
+func WithBaseURL(base *url.URL) Option {
+	return func(p *Parser) {
+		p.baseURL = base
+	}
+}
+
+// WithSafeDialer refuses to fetch anything but public http and https addresses on the given ports,
+// 80 and 443 when none are given. Loopback, private, link-local, multicast and other internal
+// addresses are refused with a *BlockedError, also after redirects and whatever the DNS answers.
+// Addresses are checked when connecting, with a transport other than an *http.Transport every fetch
+// is refused. Behind a proxy, from WithProxy or the environment, the target is checked instead and
+// the proxy is only given its address, http, https and SOCKS5 proxies are supported.
+func WithSafeDialer(ports ...int) Option {
+	return func(p *Parser) {
+		p.safeDialer = newSafeDialer(ports)
+	}
+}
+
+// WithMaxTokens limits the number of HTML tokens read, parsing stops there and the result is marked Truncated
+func WithMaxTokens(tokens int) Option {
+	return func(p *Parser) {
+		p.maxTokens = tokens
+	}
+}
+
+// WithMaxParseTime limits the wall time of a parse, including the fetch for ParseURL.
+// Parsing stops there and the result is marked Truncated, a fetch that has not
+// answered yet fails with context.DeadlineExceeded.
+func WithMaxParseTime(d time.Duration) Option {
+	return func(p *Parser) {
+		p.maxParseTime = d
+	}
+}
+
+// WithMicrodata extracts schema.org microdata (itemscope, itemprop) into Result.Microdata.
+// Microdata lives in the body, so the whole document is read.
+func WithMicrodata() Option {
+	return func(p *Parser) {
+		p.microdata = true
+	}
+}
+
+// WithRDFa extracts RDFa Lite (vocab, prefix, typeof, property, resource) into Result.RDFa.
+// Like microdata it is read from the whole document.
+func WithRDFa() Option {
+	return func(p *Parser) {
+		p.rdfa = true
+	}
+}
+
+// WithOEmbed makes ParseURL fetch the oEmbed response of the page into Result.OEmbed,
+// from the endpoint the page advertises or else from the matching provider
+func WithOEmbed() Option {
+	return func(p *Parser) {
+		p.oembed = true
+	}
+}
+
+// WithOEmbedProviders replaces DefaultOEmbedProviders as the registry used by WithOEmbed
+func WithOEmbedProviders(providers ...OEmbedProvider) Option {
+	return func(p *Parser) {
+		p.oembedProviders = append([]OEmbedProvider{}, providers...)
+	}
+}
+
+// WithManifest makes ParseURL fetch the Web App Manifest of the page into Result.Manifest,
+// its icons are added to Result.Favicons
+func WithManifest() Option {
+	return func(p *Parser) {
+		p.manifest = true
+	}
+}
+
+// WithBodyScan reads the whole document for fallbacks found in the content of the page
+// into Result.Heuristics, and for the JSON-LD blocks of the body
+func WithBodyScan() Option {
+	return func(p *Parser) {
+		p.bodyScan = true
+	}
+}
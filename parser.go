@@ -1,124 +1,278 @@
 package parser
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
 
-const (
-	httpClientTimeoutSeconds = 30
-)
-
-// Parser ...
+// Parser holds the configuration used to fetch and parse documents. Parse, ParseReader,
+// ParseURL and FetchHTML do not modify it, so a Parser can be shared between goroutines.
 type Parser struct {
+	// Result is filled by ParseHTML and ParseHTMLWithResult for callers reading p.Title and
+	// the like. It is the only state of a Parser and is only written under mu, so these calls
+	// and Reset are safe for concurrent use as well. Reading its fields while another goroutine
+	// calls them is not, ParseHTMLWithResult returns the Result of its own call instead.
 	Result
+	mu sync.Mutex
+
+	client    *http.Client
+	transport http.RoundTripper
+	proxy     func(*http.Request) (*url.URL, error)
+	timeout   time.Duration
+	header    http.Header
+
+	maxBodySize  int64
+	maxTokens    int
+	maxParseTime time.Duration
+	baseURL      *url.URL
+	safeDialer   *safeDialer
+	microdata    bool
+	rdfa         bool
+	bodyScan     bool
+
+	oembed          bool
+	oembedProviders []OEmbedProvider
+	manifest        bool
+}
+
+// New returns a Parser configured by opts
+func New(opts ...Option) *Parser {
+	p := &Parser{
+		header: http.Header{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.client = p.buildClient()
+
+	return p
 }
 
-// New ...
-func New() *Parser {
-	return &Parser{}
+// Parse parses the html read from r with a Parser configured by opts
+func Parse(r io.Reader, opts ...Option) (*Result, error) {
+	return New(opts...).ParseReader(context.Background(), r)
 }
 
-// FetchHTML returns buffer
-func (p *Parser) FetchHTML(target string) (io.ReadCloser, error) {
-	target = strings.TrimSpace(target)
+// ParseReader parses the html read from r, giving up as soon as ctx is done
+func (p *Parser) ParseReader(ctx context.Context, r io.Reader) (*Result, error) {
+	budget, cancel := p.withBudget(ctx)
+	defer cancel()
 
-	return fetch(target)
+	return p.parse(ctx, budget, r)
 }
 
-func fetch(target string) (io.ReadCloser, error) {
-	var netClient = &http.Client{
-		Timeout: time.Second * httpClientTimeoutSeconds,
-	}
-	resp, err := netClient.Get(target)
+// ParseURL fetches and parses the page at target, giving up as soon as ctx is done.
+// A cancelled or expired ctx yields ctx.Err() and no Result.
+func (p *Parser) ParseURL(ctx context.Context, target string) (*Result, error) {
+	// The time budget covers the fetch, so a slow body is cut off by the transport
+	budget, cancel := p.withBudget(ctx)
+	defer cancel()
+
+	buffer, err := p.FetchHTMLContext(budget, target)
 	if err != nil {
 		return nil, err
 	}
+	defer buffer.Close()
 
-	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
-		return nil, errors.New("page not found")
+	result, err := p.parse(ctx, budget, buffer)
+	if err != nil {
+		return nil, err
+	}
+	if p.oembed {
+		documentURL := target
+		if resp, ok := buffer.(*response); ok {
+			documentURL = resp.url.String()
+		}
+		if err := p.resolveOEmbed(ctx, budget, result, documentURL); err != nil {
+			return nil, err
+		}
 	}
+	if p.manifest {
+		if err := p.resolveManifest(ctx, budget, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
 
-	return resp.Body, nil
+// Reset clears the Result filled by ParseHTML
+func (p *Parser) Reset() {
+	p.mu.Lock()
+	p.Result = Result{}
+	p.mu.Unlock()
 }
 
-// ParseHTMLWithResult parses given html and returns a Result
+// ParseHTMLWithResult parses given html into p.Result and returns a copy of it, which later
+// calls from other goroutines do not change
 func (p *Parser) ParseHTMLWithResult(buffer io.ReadCloser) (*Result, error) {
-	err := p.ParseHTML(buffer)
-	if err == nil {
-		result := &Result{
-			Title:       p.Title,
-			Description: p.Description,
-			OpenGraph:   p.OpenGraph,
-
-			Images: p.Images,
-			Videos: p.Videos,
-			Audios: p.Audios,
-
-			Music:   p.Music,
-			Article: p.Article,
-			Book:    p.Book,
-			Profile: p.Profile,
+	return p.ParseHTMLWithResultContext(context.Background(), buffer)
+}
 
-			Favicons: p.Favicons,
+// ParseHTMLWithResultContext is like ParseHTMLWithResult but stops when ctx is done
+func (p *Parser) ParseHTMLWithResultContext(ctx context.Context, buffer io.ReadCloser) (*Result, error) {
+	defer buffer.Close()
 
-			Twitter: p.Twitter,
-		}
-		return result, nil
-	} else {
+	result, err := p.ParseReader(ctx, buffer)
+	if err != nil {
 		return nil, err
 	}
+	p.mu.Lock()
+	p.Result = *result
+	p.mu.Unlock()
+	return result, nil
 }
 
-// ParseHTML parses given html
+// ParseHTML parses given html into p.Result, replacing what a previous call left there
 func (p *Parser) ParseHTML(buffer io.ReadCloser) error {
-	defer buffer.Close()
+	return p.ParseHTMLContext(context.Background(), buffer)
+}
+
+// ParseHTMLContext parses given html, it returns ctx.Err() if ctx is done before the head is read
+func (p *Parser) ParseHTMLContext(ctx context.Context, buffer io.ReadCloser) error {
+	_, err := p.ParseHTMLWithResultContext(ctx, buffer)
+	return err
+}
+
+// withBudget derives the context bounding the time set by WithMaxParseTime
+func (p *Parser) withBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.maxParseTime > 0 {
+		return context.WithTimeout(ctx, p.maxParseTime)
+	}
+	return context.WithCancel(ctx)
+}
 
-	z := html.NewTokenizer(buffer)
+// parse stops with ctx.Err() when the caller's ctx is done, running out of budget
+// or of the other limits only marks the result as truncated
+func (p *Parser) parse(ctx, budget context.Context, input io.Reader) (*Result, error) {
+	result := &Result{}
+
+	body := ioutil.NopCloser(input)
+	contentType, documentURL := "", p.baseURL
+	if resp, ok := input.(*response); ok {
+		contentType, documentURL = resp.contentType, resp.url
+	} else if p.maxBodySize > 0 {
+		body = &maxBytesReader{ReadCloser: body, remaining: p.maxBodySize}
+	}
+	r, charset := decodeCharset(&contextReader{ctx: budget, ReadCloser: body}, contentType)
+	result.Charset = charset
+
+	// Extractors working on the whole document get a copy of everything the tokenizer reads
+	var document *bytes.Buffer
+	if p.needsDocument() {
+		document = &bytes.Buffer{}
+		r = io.TeeReader(r, document)
+	}
+
+	z := html.NewTokenizer(r)
 	extractTitle := false
+	// Holds the text of the current <script type="application/ld+json">, nil outside of one
+	var jsonld []byte
+	// Number of JSON-LD blocks read, the body scan skips them
+	jsonldBlocks := 0
+	baseHref := ""
+	// Keys declared with property, and the tags applied once the head is read, in document order
+	declared := make(map[string]bool)
+	var tags []headTag
+	// Prefixes declared on <html> and <head>, mapping custom names to the OpenGraph vocabularies
+	prefixes := map[string]string{}
+	tokens := 0
+tokenize:
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if p.maxTokens > 0 && tokens >= p.maxTokens {
+			result.Truncated = true
+			break tokenize
+		}
+
 		token := z.Next()
+		tokens++
 		switch token {
 		case html.ErrorToken:
 			if z.Err() == io.EOF {
-				return nil
+				break tokenize
 			}
-			return z.Err()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if budget.Err() != nil || errors.Is(z.Err(), ErrBodyTooLarge) {
+				result.Truncated = true
+				break tokenize
+			}
+			return nil, z.Err()
 		case html.TextToken:
 			// Text cannot be extract from the tag so it must extracted here
 			// extractTitle acts as a flag which is set true on opening title tag and false on closing title tag
 			if extractTitle {
-				p.Title = string(z.Text())
+				result.Title = string(z.Text())
+			}
+			if jsonld != nil {
+				jsonld = append(jsonld, z.Text()...)
 			}
 		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
 			name, hasAttr := z.TagName()
 			if atom.Lookup(name) == atom.Body {
-				return nil
+				break tokenize
 			}
 			if atom.Lookup(name) == atom.Title {
 				// Toggle title parsing
 				extractTitle = !extractTitle
+			} else if atom.Lookup(name) == atom.Script {
+				if jsonld != nil {
+					result.parseJSONLD(jsonld)
+					jsonld = nil
+					jsonldBlocks++
+				}
+				if token == html.StartTagToken && hasAttr && isJSONLDScript(getAttributes(z)["type"]) {
+					jsonld = []byte{}
+				}
 			} else if hasAttr {
 				attrs := getAttributes(z)
 				if atom.Lookup(name) == atom.Meta {
 					// Parse HTML meta tag
-					if _, ok := attrs["property"]; ok {
-						// tag with <meta property="..." content="..." ...>
-						p.ParseMetaProperty(attrs)
+					if property, ok := attrs["property"]; ok {
+						// tag with <meta property="..." content="..." ...>, ogp:title or http://ogp.me/ns#title becoming og:title
+						property = normalizeProperty(property, prefixes)
+						attrs["property"] = property
+						tags = append(tags, headTag{attrs: attrs, apply: result.ParseMetaProperty})
+						declared[property] = true
 					} else if name, ok := attrs["name"]; ok {
-						// Description meta tag
+						// Description and author meta tags
 						if name == "description" {
-							p.Description = attrs["content"]
+							result.Description = attrs["content"]
+						} else if name == "author" {
+							result.Author = attrs["content"]
+						} else if isPropertyName(name) {
+							// tag with <meta name="twitter:..." content="..." ...>
+							attrs["property"] = name
+							tags = append(tags, headTag{attrs: attrs, apply: result.ParseMetaProperty, named: true})
+						} else if name == "apple-itunes-app" {
+							tags = append(tags, headTag{attrs: attrs, apply: result.parseITunesAppMeta})
+						} else if dublinCoreElement(name) != "" {
+							result.parseDublinCoreMeta(attrs)
+						} else if isCitationName(name) {
+							result.parseCitationMeta(attrs)
 						}
 					}
 				} else if atom.Lookup(name) == atom.Link {
-					p.ParseLink(attrs)
+					tags = append(tags, headTag{attrs: attrs, apply: result.ParseLink})
+				} else if atom.Lookup(name) == atom.Base && baseHref == "" {
+					// Only the first <base href> counts
+					baseHref = attrs["href"]
+				} else if (atom.Lookup(name) == atom.Html || atom.Lookup(name) == atom.Head) && attrs["prefix"] != "" {
+					prefixes = parsePrefixes(prefixes, attrs["prefix"])
 				} else {
 					continue
 				}
@@ -127,6 +281,121 @@ func (p *Parser) ParseHTML(buffer io.ReadCloser) error {
 			}
 		}
 	}
+
+	// Tags are applied in document order so repeated structures such as og:image and og:image:width
+	// stay grouped. A property declaration wins over a name declaration of the same key wherever
+	// they appear, every name declaration of that key is dropped so it is not collected twice.
+	for _, tag := range tags {
+		if tag.named && declared[tag.attrs["property"]] {
+			continue
+		}
+		tag.apply(tag.attrs)
+	}
+
+	result.resolveURLs(documentURL, baseHref)
+
+	if document != nil {
+		if err := p.parseDocument(ctx, budget, z, tokens, document, result, jsonldBlocks); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// headTag is a <meta> or <link> tag, named when a meta used name for a property key
+type headTag struct {
+	attrs map[string]string
+	apply func(map[string]string)
+	named bool
+}
+
+func (p *Parser) needsDocument() bool {
+	return p.microdata || p.rdfa || p.bodyScan
+}
+
+// parseDocument reads the rest of the document and runs the extractors that need all of it,
+// the limits apply as for the head. z goes on from where the head loop stopped after tokens
+// tokens, and the first jsonldBlocks JSON-LD blocks were read by the head loop.
+func (p *Parser) parseDocument(ctx, budget context.Context, z *html.Tokenizer, tokens int, document *bytes.Buffer, result *Result, jsonldBlocks int) error {
+	// Tokenizing rather than copying the rest stops reading at the token limit
+	for !result.Truncated {
+		if p.maxTokens > 0 && tokens >= p.maxTokens {
+			result.Truncated = true
+			break
+		}
+		tokens++
+		if z.Next() != html.ErrorToken {
+			continue
+		}
+		if z.Err() == io.EOF {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if budget.Err() == nil && !errors.Is(z.Err(), ErrBodyTooLarge) {
+			return z.Err()
+		}
+		result.Truncated = true
+	}
+	// The tokenizer reads ahead, the document is cut where it stopped
+	if p.maxTokens > 0 && limitTokens(document, p.maxTokens) {
+		result.Truncated = true
+	}
+
+	doc, err := html.Parse(document)
+	if err != nil {
+		return err
+	}
+	if p.microdata {
+		result.parseMicrodata(doc)
+	}
+	if p.rdfa {
+		result.parseRDFa(doc)
+	}
+	if p.bodyScan {
+		result.parseBody(doc, jsonldBlocks)
+	}
+	return nil
+}
+
+// limitTokens cuts document after its first max tokens, it reports whether anything was cut
+func limitTokens(document *bytes.Buffer, max int) bool {
+	z := html.NewTokenizer(bytes.NewReader(document.Bytes()))
+	size := 0
+	for tokens := 0; tokens < max; tokens++ {
+		if z.Next() == html.ErrorToken {
+			return false
+		}
+		size += len(z.Raw())
+	}
+	if size == document.Len() {
+		return false
+	}
+	document.Truncate(size)
+	return true
+}
+
+// Namespaces also published as <meta name="..."> by many sites, twitter cards are even specified that way
+var propertyNamePrefixes = []string{"twitter:", "og:", "article:", "al:"}
+
+func isPropertyName(name string) bool {
+	for _, prefix := range propertyNamePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRel reports whether the space separated rel attribute holds value, ignoring case
+func hasRel(rel, value string) bool {
+	for _, field := range strings.Fields(rel) {
+		if strings.EqualFold(field, value) {
+			return true
+		}
+	}
+	return false
 }
 
 func getAttributes(z *html.Tokenizer) map[string]string {
@@ -142,49 +411,73 @@ func getAttributes(z *html.Tokenizer) map[string]string {
 }
 
 // ParseMetaProperty processes meta attributes
-func (p *Parser) ParseMetaProperty(attrs map[string]string) {
+func (result *Result) ParseMetaProperty(attrs map[string]string) {
 	switch attrs["property"] {
 	// opengraph:basic
 	case "og:title", "og:type", "og:url", "og:description", "og:determiner", "og:locale", "og:locale:alternate", "og:site_name":
-		p.parseBasicOGMeta(attrs)
+		result.parseBasicOGMeta(attrs)
 	// opengraph:image
 	case "og:image", "og:image:url", "og:image:secure_url", "og:image:type", "og:image:width", "og:image:height", "og:image:alt":
-		p.parseImageMeta(attrs)
+		result.parseImageMeta(attrs)
 	// opengraph:video
 	case "og:video", "og:video:url", "og:video:secure_url", "og:video:type", "og:video:width", "og:video:height",
 		"video:actor", "video:actor:role", "video:director", "video:writer", "video:duration", "video:release_date", "video:tag", "video:series":
-		p.parseVideoMeta(attrs)
+		result.parseVideoMeta(attrs)
 	// opengraph:audio
 	case "og:audio", "og:audio:secure_url", "og:audio:type":
-		p.parseAudioMeta(attrs)
+		result.parseAudioMeta(attrs)
 	// music
 	case "music:musician", "music:album", "music:album:disc", "music:album:track", "music:song",
 		"music:song:disc", "music:song:track", "music:release_date", "music:creator", "music:duration":
-		p.parseMusicMeta(attrs)
+		result.parseMusicMeta(attrs)
 	// article
 	case "article:published_time", "article:modified_time", "article:expiration_time", "article:author",
 		"article:section", "article:tag":
-		p.parseArticleMeta(attrs)
+		result.parseArticleMeta(attrs)
 	// book
 	case "book:author", "book:isbn", "book:release_date", "book:tag":
-		p.parseBookMeta(attrs)
+		result.parseBookMeta(attrs)
 	// profile
 	case "profile:first_name", "profile:last_name", "profile:username", "profile:gender":
-		p.parseProfileMeta(attrs)
+		result.parseProfileMeta(attrs)
+	// product
+	case "product:price:amount", "product:price:currency", "og:price:amount", "og:price:currency",
+		"product:original_price:amount", "product:original_price:currency", "product:sale_price:amount",
+		"product:sale_price:currency", "product:availability", "og:availability", "product:condition",
+		"product:brand", "og:brand", "product:retailer_item_id", "product:category":
+		result.parseProductMeta(attrs)
+	// business
+	case "business:contact_data:street_address", "business:contact_data:locality", "business:contact_data:region",
+		"business:contact_data:postal_code", "business:contact_data:country_name", "business:contact_data:email",
+		"business:contact_data:phone_number", "business:contact_data:fax_number", "business:contact_data:website":
+		result.parseBusinessMeta(attrs)
+	// place
+	case "place:location:latitude", "place:location:longitude", "place:location:altitude":
+		result.parsePlaceMeta(attrs)
 	// twitter
 	case "twitter:card", "twitter:site", "twitter:site:id", "twitter:creator", "twitter:creator:id",
 		"twitter:description", "twitter:title", "twitter:image", "twitter:image:alt", "twitter:player",
-		"twitter:player:height", "twitter:player:width", "twitter:player:stream", "twitter:app:name:iphone",
-		"twitter:app:url:iphone", "twitter:app:id:iphone", "twitter:app:name:ipad", "twitter:app:url:ipad",
-		"twitter:app:id:ipad", "twitter:app:name:googleplay", "twitter:app:url:googleplay", "twitter:app:id:googleplay":
-		p.parseTwitterMeta(attrs)
+		"twitter:player:height", "twitter:player:width", "twitter:player:stream":
+		result.parseTwitterMeta(attrs)
+	default:
+		// App Links and twitter apps, platforms are open-ended
+		if isAppLinkProperty(attrs["property"]) {
+			result.parseAppLinkMeta(attrs)
+		} else if isTwitterAppProperty(attrs["property"]) {
+			result.parseTwitterAppMeta(attrs)
+		}
 	}
 }
 
-func (p *Parser) ParseLink(attrs map[string]string) {
+// ParseLink processes link attributes
+func (result *Result) ParseLink(attrs map[string]string) {
 	// Not using a switch/case because there is too much variation in naming the favicon
 	// but it often includes 'icon' in the rel attribute
 	if strings.Contains(attrs["rel"], "icon") {
-		p.parseFaviconLink(attrs)
+		result.parseFaviconLink(attrs)
+	} else if hasRel(attrs["rel"], "alternate") && isOEmbedType(attrs["type"]) {
+		result.parseOEmbedLink(attrs)
+	} else {
+		result.parseRelLink(attrs)
 	}
 }
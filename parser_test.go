@@ -1,8 +1,13 @@
 package parser_test
 
 import (
+	"context"
+	"io"
 	"io/ioutil"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	parser "github.com/ammit/go-metaparser"
@@ -125,7 +130,7 @@ const html = `
 	<meta property="twitter:app:id:ipad" content="1" />
 	<meta property="twitter:app:name:googleplay" content="test" />
 	<meta property="twitter:app:url:googleplay" content="test" />
-	<meta property="twitter:app:id:googleplay" content="1" />
+	<meta property="twitter:app:id:googleplay" content="com.example.app" />
 </head>
 <body>
 </body>
@@ -470,56 +475,32 @@ func TestParserParseHTML(t *testing.T) {
 		t.Error("twitter:player:stream parsed incorrectly")
 	}
 
-	// log.Fatalf("%+v", p.Twitter.Apps)
-
-	if len(p.Twitter.Apps[0].Name) == 0 {
-		t.Error("twitter:app:name:iphone parsed incorrectly")
-	}
-
-	if len(p.Twitter.Apps[0].URL) == 0 {
-		t.Error("twitter:app:url:iphone parsed incorrectly")
-	}
-
-	if p.Twitter.Apps[0].ID == 0 {
-		t.Error("twitter:app:id:iphone parsed incorrectly")
-	}
-
-	if len(p.Twitter.Apps[0].Type) == 0 {
-		t.Error("twitter:app:type:iphone parsed incorrectly")
-	}
-
-	if len(p.Twitter.Apps[0].Name) == 0 {
-		t.Error("twitter:app:name:ipad parsed incorrectly")
+	if len(p.Twitter.Apps) != 3 {
+		t.Fatalf("twitter:app:* parsed incorrectly: %d apps", len(p.Twitter.Apps))
 	}
 
-	if len(p.Twitter.Apps[0].URL) == 0 {
-		t.Error("twitter:app:url:ipad parsed incorrectly")
-	}
-
-	if p.Twitter.Apps[0].ID == 0 {
-		t.Error("twitter:app:id:ipad parsed incorrectly")
-	}
-
-	if len(p.Twitter.Apps[0].Type) == 0 {
-		t.Error("twitter:app:type:ipad parsed incorrectly")
-	}
-
-	if len(p.Twitter.Apps[0].Name) == 0 {
-		t.Error("twitter:app:name:googleplay parsed incorrectly")
+	for i, platform := range []string{"iphone", "ipad", "googleplay"} {
+		app := p.Twitter.Lookup(platform)
+		if app == nil || app != p.Twitter.Apps[i] {
+			t.Errorf("twitter:app:*:%s parsed out of order", platform)
+			continue
+		}
+		if len(app.Name) == 0 {
+			t.Errorf("twitter:app:name:%s parsed incorrectly", platform)
+		}
+		if len(app.URL) == 0 {
+			t.Errorf("twitter:app:url:%s parsed incorrectly", platform)
+		}
 	}
 
-	if len(p.Twitter.Apps[0].URL) == 0 {
-		t.Error("twitter:app:url:googleplay parsed incorrectly")
+	if p.Twitter.Apps[0].ID != "1" || p.Twitter.Apps[1].ID != "1" {
+		t.Error("twitter:app:id:iphone or twitter:app:id:ipad parsed incorrectly")
 	}
 
-	if p.Twitter.Apps[0].ID == 0 {
+	if p.Twitter.Apps[2].ID != "com.example.app" {
 		t.Error("twitter:app:id:googleplay parsed incorrectly")
 	}
 
-	if len(p.Twitter.Apps[0].Type) == 0 {
-		t.Error("twitter:app:type:googleplay parsed incorrectly")
-	}
-
 	if len(p.Favicons) != 2 {
 		t.Error("Favicons parsed incorrectly")
 	}
@@ -569,3 +550,907 @@ func BenchmarkParser(b *testing.B) {
 		_ = p.ParseHTML(ioutil.NopCloser(strings.NewReader(html)))
 	}
 }
+
+func TestParserParseHTMLCharset(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		title   string
+		charset string
+	}{
+		{
+			name:    "meta charset",
+			html:    "<html><head><meta charset=\"Shift_JIS\"><title>\x93\xfa\x96\x7b</title></head>",
+			title:   "日本",
+			charset: "shift_jis",
+		},
+		{
+			name:    "meta http-equiv",
+			html:    "<html><head><meta http-equiv=\"Content-Type\" content=\"text/html; charset=ISO-8859-1\"><title>caf\xe9</title></head>",
+			title:   "café",
+			charset: "windows-1252",
+		},
+		{
+			name:    "byte order mark",
+			html:    "\xef\xbb\xbf<html><head><meta charset=\"windows-1251\"><title>ok</title></head>",
+			title:   "ok",
+			charset: "utf-8",
+		},
+		{
+			name:    "undeclared",
+			html:    "<html><head><title>日本</title></head>",
+			title:   "日本",
+			charset: "utf-8",
+		},
+	}
+
+	for _, test := range tests {
+		p := parser.New()
+		err := p.ParseHTML(ioutil.NopCloser(strings.NewReader(test.html)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if p.Title != test.title {
+			t.Errorf("%s: title decoded incorrectly: %q", test.name, p.Title)
+		}
+
+		if p.Charset != test.charset {
+			t.Errorf("%s: charset detected incorrectly: %q", test.name, p.Charset)
+		}
+	}
+}
+
+func TestParserResolveURLs(t *testing.T) {
+	const relativeHtml = `
+<html>
+<head>
+	<base href="/assets/">
+	<link rel="icon" href="icon.png">
+	<link rel="apple-touch-icon" href="./icon.png">
+	<meta property="og:url" content="//example.com/page">
+	<meta property="og:image" content="ogp.jpg">
+	<meta property="og:image:secure_url" content="https://cdn.example.com/ogp.jpg">
+	<meta property="twitter:image" content="../twitter.jpg">
+</head>
+`
+	base, _ := url.Parse("https://example.com/blog/post")
+	p := parser.New(parser.WithBaseURL(base))
+	result, err := p.ParseHTMLWithResult(ioutil.NopCloser(strings.NewReader(relativeHtml)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.BaseURL != "https://example.com/assets/" {
+		t.Errorf("base url resolved incorrectly: %v", result.BaseURL)
+	}
+
+	if result.Favicons[0].URL != "https://example.com/assets/icon.png" {
+		t.Errorf("favicon url resolved incorrectly: %v", result.Favicons[0].URL)
+	}
+
+	// Both favicons resolve to the same URL, the one resolved last is kept
+	if result.RawURL(result.Favicons[0].URL) != "./icon.png" {
+		t.Error("raw favicon url not kept")
+	}
+
+	if result.OpenGraph.URL != "https://example.com/page" {
+		t.Errorf("og:url resolved incorrectly: %v", result.OpenGraph.URL)
+	}
+
+	if result.Images[0].URL != "https://example.com/assets/ogp.jpg" {
+		t.Errorf("og:image resolved incorrectly: %v", result.Images[0].URL)
+	}
+
+	if result.Images[0].SecureURL != "https://cdn.example.com/ogp.jpg" {
+		t.Errorf("absolute og:image:secure_url changed: %v", result.Images[0].SecureURL)
+	}
+
+	if result.Twitter.Image != "https://example.com/twitter.jpg" {
+		t.Errorf("twitter:image resolved incorrectly: %v", result.Twitter.Image)
+	}
+}
+
+func TestParserParseJSONLD(t *testing.T) {
+	const jsonldHtml = `
+<html>
+<head>
+	<script type="application/ld+json">
+	{
+		"@context": "https://schema.org",
+		"@graph": [
+			{"@type": "Organization", "name": "Example News", "logo": {"@type": "ImageObject", "url": "https://example.com/logo.png"}},
+			{
+				"@type": "NewsArticle",
+				"headline": "Go Meta Parser",
+				"image": ["https://example.com/1.jpg", "https://example.com/2.jpg"],
+				"author": [{"@type": "Person", "name": "Jane"}, "John"],
+				"datePublished": "2020-04-20",
+				"keywords": "go, html"
+			},
+			{
+				"@type": "BreadcrumbList",
+				"itemListElement": [
+					{"@type": "ListItem", "position": 1, "name": "Home", "item": "https://example.com/"},
+					{"@type": "ListItem", "position": "2", "item": {"@id": "https://example.com/go", "name": "Go"}}
+				]
+			}
+		]
+	}
+	</script>
+	<script type="application/ld+json">
+	[{"@type": "Product", "name": "Gopher", "offers": {"@type": "Offer", "price": 9.5, "priceCurrency": "EUR"}},
+	 {"@type": "Recipe", "name": "Soup", "recipeInstructions": [{"@type": "HowToStep", "text": "Boil"}, "Serve"]}]
+	</script>
+	<script type="application/ld+json">{ not json </script>
+	<script>var ignored = {"@type": "Product"};</script>
+</head>
+`
+	p := parser.New()
+	result, err := p.ParseHTMLWithResult(ioutil.NopCloser(strings.NewReader(jsonldHtml)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.JSONLD.Blocks) != 2 {
+		t.Errorf("json-ld blocks parsed incorrectly: %d", len(result.JSONLD.Blocks))
+	}
+
+	articles := result.JSONLD.Articles()
+	if len(articles) != 1 {
+		t.Fatal("json-ld articles parsed incorrectly")
+	}
+	if articles[0].Headline != "Go Meta Parser" || articles[0].Type != "NewsArticle" {
+		t.Error("json-ld article headline parsed incorrectly")
+	}
+	if len(articles[0].Images) != 2 || len(articles[0].Authors) != 2 || articles[0].Authors[1].Name != "John" {
+		t.Error("json-ld article images or authors parsed incorrectly")
+	}
+	if len(articles[0].Keywords) != 2 {
+		t.Error("json-ld article keywords parsed incorrectly")
+	}
+
+	organizations := result.JSONLD.Organizations()
+	if len(organizations) != 1 || organizations[0].Logo != "https://example.com/logo.png" {
+		t.Error("json-ld organization parsed incorrectly")
+	}
+
+	breadcrumbs := result.JSONLD.BreadcrumbLists()
+	if len(breadcrumbs) != 1 || len(breadcrumbs[0].Items) != 2 {
+		t.Fatal("json-ld breadcrumbs parsed incorrectly")
+	}
+	if breadcrumbs[0].Items[1].Position != 2 || breadcrumbs[0].Items[1].Name != "Go" || breadcrumbs[0].Items[1].URL != "https://example.com/go" {
+		t.Error("json-ld breadcrumb item parsed incorrectly")
+	}
+
+	products := result.JSONLD.Products()
+	if len(products) != 1 || len(products[0].Offers) != 1 || products[0].Offers[0].Price != "9.5" {
+		t.Error("json-ld product parsed incorrectly")
+	}
+
+	recipes := result.JSONLD.Recipes()
+	if len(recipes) != 1 || len(recipes[0].Instructions) != 2 {
+		t.Error("json-ld recipe parsed incorrectly")
+	}
+}
+
+func TestParserParseMetaName(t *testing.T) {
+	const nameHtml = `
+<html>
+<head>
+	<meta name="twitter:card" content="summary_large_image">
+	<meta name="twitter:title" content="name title">
+	<meta name="og:title" content="name og title">
+	<meta property="og:title" content="property og title">
+	<meta name="article:tag" content="name tag">
+	<meta name="og:image" content="https://example.com/name.jpg">
+	<meta property="og:image" content="https://example.com/property.jpg">
+</head>
+`
+	p := parser.New()
+	err := p.ParseHTML(ioutil.NopCloser(strings.NewReader(nameHtml)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Twitter.Card != "summary_large_image" || p.Twitter.Title != "name title" {
+		t.Error("twitter name tags parsed incorrectly")
+	}
+
+	if p.OpenGraph.Title != "property og title" {
+		t.Error("property declaration must win over name declaration")
+	}
+
+	if len(p.Article.Tags) != 1 || p.Article.Tags[0] != "name tag" {
+		t.Error("article name tags parsed incorrectly")
+	}
+
+	if len(p.Images) != 1 || p.Images[0].URL != "https://example.com/property.jpg" {
+		t.Error("name declarations of a key declared with property must be ignored")
+	}
+}
+
+func TestParserParseMetaNameOrder(t *testing.T) {
+	const mixedHtml = `
+<html>
+<head>
+	<meta property="og:image" content="https://example.com/a.jpg">
+	<meta name="og:image:width" content="100">
+	<meta property="og:image" content="https://example.com/b.jpg">
+	<meta name="og:image:width" content="200">
+</head>
+`
+	result, err := parser.Parse(strings.NewReader(mixedHtml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Images) != 2 || result.Images[0].Width != 100 || result.Images[1].Width != 200 {
+		t.Errorf("name declarations must be applied in document order: %+v %+v", result.Images[0], result.Images[1])
+	}
+}
+
+func TestResultPreview(t *testing.T) {
+	const previewHtml = `
+<html>
+<head>
+	<title>Page title</title>
+	<meta name="description" content="Page description">
+	<meta name="author" content="Meta Author">
+	<meta name="twitter:title" content="Twitter title">
+	<meta name="twitter:image" content="https://example.com/twitter.jpg">
+	<meta name="twitter:creator" content="@gopher">
+	<meta name="twitter:site" content="@example">
+	<meta property="og:site_name" content="Example">
+	<link rel="icon" href="https://example.com/16.png" sizes="16x16">
+	<link rel="apple-touch-icon" href="https://example.com/180.png" sizes="180x180">
+	<script type="application/ld+json">
+	{"@type": "Article", "headline": "JSON-LD headline", "datePublished": "2020-04-20", "author": {"@type": "Person", "name": "Jane"}}
+	</script>
+</head>
+`
+	p := parser.New()
+	result, err := p.ParseHTMLWithResult(ioutil.NopCloser(strings.NewReader(previewHtml)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	preview := result.Preview()
+	if preview.Title != "Twitter title" {
+		t.Errorf("preview title taken from the wrong source: %v", preview.Title)
+	}
+	if preview.Description != "Page description" {
+		t.Errorf("preview description taken from the wrong source: %v", preview.Description)
+	}
+	if preview.SiteName != "Example" {
+		t.Errorf("preview site name taken from the wrong source: %v", preview.SiteName)
+	}
+	if preview.Image == nil || preview.Image.URL != "https://example.com/twitter.jpg" {
+		t.Error("preview image taken from the wrong source")
+	}
+	if preview.Icon != "https://example.com/180.png" {
+		t.Errorf("preview icon is not the largest favicon: %v", preview.Icon)
+	}
+	if preview.Author != "Jane" || preview.PublishedTime != "2020-04-20" {
+		t.Error("preview author or published time taken from the wrong source")
+	}
+
+	preview = result.PreviewWith(parser.SourceMeta, parser.SourceJSONLD)
+	if preview.Title != "Page title" || preview.Author != "Meta Author" {
+		t.Error("preview does not follow the given precedence")
+	}
+	if preview.Icon != "" {
+		t.Error("preview must not use sources left out of the precedence")
+	}
+
+	if preview = result.PreviewWith(parser.SourceTwitter); preview.SiteName != "" || preview.Author != "" {
+		t.Errorf("twitter handles must not be used as the site or author name: %q %q", preview.SiteName, preview.Author)
+	}
+
+	const videoHtml = `
+<html>
+<head>
+	<script type="application/ld+json">
+	[{"@type": "Article", "description": "Article description", "datePublished": "2020-04-20"},
+	 {"@type": "VideoObject", "name": "Video name", "description": "Video description", "uploadDate": "2020-05-01"}]
+	</script>
+</head>
+`
+	result, err = parser.Parse(strings.NewReader(videoHtml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	preview = result.PreviewWith(parser.SourceJSONLD)
+	if preview.Title != "Video name" || preview.Description != "Article description" || preview.PublishedTime != "2020-04-20" {
+		t.Errorf("a video must only fill the fields the article left empty: %+v", preview)
+	}
+}
+
+func TestParserParseHTMLLimits(t *testing.T) {
+	p := parser.New(parser.WithMaxBodySize(200))
+	result, err := p.ParseHTMLWithResult(ioutil.NopCloser(strings.NewReader(html)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Truncated || result.Title != "Go Meta Parser" || result.OpenGraph.Title != "" {
+		t.Error("body size limit not applied")
+	}
+
+	p = parser.New(parser.WithMaxTokens(20))
+	result, err = p.ParseHTMLWithResult(ioutil.NopCloser(strings.NewReader(html)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Truncated || result.Title != "Go Meta Parser" || result.OpenGraph.Title != "" {
+		t.Error("token limit not applied")
+	}
+
+	p = parser.New()
+	result, err = p.ParseHTMLWithResult(ioutil.NopCloser(strings.NewReader(html)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Truncated {
+		t.Error("complete parse marked as truncated")
+	}
+}
+
+func TestParserReuse(t *testing.T) {
+	p := parser.New()
+	for i := 0; i < 2; i++ {
+		if err := p.ParseHTML(ioutil.NopCloser(strings.NewReader(html))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(p.Images) != 1 || len(p.OpenGraph.LocalesAlternate) != 2 {
+		t.Error("parsing twice must not accumulate results")
+	}
+
+	p.Reset()
+	if p.Title != "" || len(p.Images) != 0 {
+		t.Error("Reset does not clear the result")
+	}
+}
+
+func TestParserConcurrentParse(t *testing.T) {
+	p := parser.New()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := p.ParseReader(context.Background(), strings.NewReader(html))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(result.Images) != 1 || result.Title != "Go Meta Parser" {
+				t.Error("concurrent parses share state")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p.Title != "" {
+		t.Error("ParseReader must not modify the parser")
+	}
+}
+
+func TestParserConcurrentParseHTML(t *testing.T) {
+	p := parser.New()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			title := "Page " + strconv.Itoa(i)
+			result, err := p.ParseHTMLWithResult(ioutil.NopCloser(strings.NewReader("<title>" + title + "</title>")))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if result.Title != title {
+				t.Errorf("got the result of another call: %q instead of %q", result.Title, title)
+			}
+			p.Reset()
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestParse(t *testing.T) {
+	result, err := parser.Parse(strings.NewReader(titleHtml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Title != "Go Meta Parser" {
+		t.Error("title parsed incorrectly")
+	}
+}
+
+func TestParserParseMicrodata(t *testing.T) {
+	const microdataHtml = `
+<html>
+<head><title>Microdata</title></head>
+<body>
+	<div itemscope itemtype="https://schema.org/Product" itemref="price">
+		<span itemprop="name">Gopher plush</span>
+		<img itemprop="image" src="/gopher.png" alt="">
+		<a itemprop="url" href="gopher">link</a>
+		<meta itemprop="sku" content="G-1">
+		<div itemprop="brand" itemscope itemtype="https://schema.org/Brand">
+			<span itemprop="name">Go</span>
+		</div>
+		<time itemprop="releaseDate" datetime="2009-11-10">launch day</time>
+	</div>
+	<p id="price"><data itemprop="price" value="9.50">9,50 EUR</data></p>
+	<div itemscope itemtype="https://schema.org/Person"><span itemprop="name given">Jane</span></div>
+</body>
+</html>
+`
+	base, _ := url.Parse("https://example.com/shop/")
+	p := parser.New(parser.WithMicrodata(), parser.WithBaseURL(base))
+	result, err := p.ParseReader(context.Background(), strings.NewReader(microdataHtml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Title != "Microdata" {
+		t.Error("title parsed incorrectly")
+	}
+
+	if len(result.Microdata) != 2 {
+		t.Fatalf("microdata top-level items parsed incorrectly: %d", len(result.Microdata))
+	}
+
+	product := result.Microdata[0]
+	if !product.HasType("Product") || product.Text("name") != "Gopher plush" {
+		t.Error("microdata item type or name parsed incorrectly")
+	}
+	if product.Text("image") != "https://example.com/gopher.png" || product.Text("url") != "https://example.com/shop/gopher" {
+		t.Error("microdata url values not resolved")
+	}
+	if product.Text("sku") != "G-1" || product.Text("releaseDate") != "2009-11-10" {
+		t.Error("microdata meta or time values parsed incorrectly")
+	}
+	if product.Text("price") != "9.50" {
+		t.Error("microdata itemref not followed")
+	}
+	if brands := product.Items("brand"); len(brands) != 1 || brands[0].Text("name") != "Go" {
+		t.Error("microdata nested item parsed incorrectly")
+	}
+	if _, ok := product.Properties["name"]; !ok || len(product.Properties["name"]) != 1 {
+		t.Error("nested item properties must not leak into the parent")
+	}
+
+	person := result.Microdata[1]
+	if person.Text("name") != "Jane" || person.Text("given") != "Jane" {
+		t.Error("microdata itemprop with several names parsed incorrectly")
+	}
+}
+
+func TestParserParseRDFa(t *testing.T) {
+	const rdfaHtml = `
+<html prefix="ogp: http://ogp.me/ns# mus: http://ogp.me/ns/music#">
+<head>
+	<title>RDFa</title>
+	<meta property="ogp:title" content="Prefixed title">
+	<meta property="http://ogp.me/ns#description" content="Full IRI description">
+	<meta property="mus:duration" content="240">
+	<meta property="fb:app_id" content="1234">
+</head>
+<body vocab="http://schema.org/">
+	<div typeof="Person" resource="#jane">
+		<span property="name">Jane</span>
+		<a property="url" href="/jane">home</a>
+		<div property="address" typeof="PostalAddress">
+			<span property="addressLocality">Berlin</span>
+		</div>
+	</div>
+</body>
+</html>
+`
+	base, _ := url.Parse("https://example.com/people/")
+	p := parser.New(parser.WithRDFa(), parser.WithBaseURL(base))
+	result, err := p.ParseReader(context.Background(), strings.NewReader(rdfaHtml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.OpenGraph.Title != "Prefixed title" {
+		t.Error("og:title with a declared prefix parsed incorrectly")
+	}
+	if result.OpenGraph.Description != "Full IRI description" {
+		t.Error("og:description as a full IRI parsed incorrectly")
+	}
+	if result.Music.Duration != 240 {
+		t.Error("music:duration with a declared prefix parsed incorrectly")
+	}
+
+	if len(result.RDFa) != 2 {
+		t.Fatalf("rdfa items parsed incorrectly: %d", len(result.RDFa))
+	}
+	if result.RDFa[0].Text("http://ogp.me/ns#title") != "Prefixed title" {
+		t.Error("rdfa document properties parsed incorrectly")
+	}
+
+	person := result.RDFa[1]
+	if len(person.Types) != 1 || person.Types[0] != "http://schema.org/Person" || person.Resource != "https://example.com/people/#jane" {
+		t.Error("rdfa typeof or resource parsed incorrectly")
+	}
+	if person.Text("http://schema.org/name") != "Jane" || person.Text("http://schema.org/url") != "https://example.com/jane" {
+		t.Error("rdfa properties parsed incorrectly")
+	}
+	if address := person.Properties["http://schema.org/address"]; len(address) != 1 || address[0].Item == nil ||
+		address[0].Item.Text("http://schema.org/addressLocality") != "Berlin" {
+		t.Error("rdfa nested item parsed incorrectly")
+	}
+}
+
+func TestParserParseLinks(t *testing.T) {
+	const linksHtml = `
+<html>
+<head>
+	<link rel="canonical" href="/post">
+	<link rel="canonical" href="/ignored">
+	<link rel="amphtml" href="https://example.com/amp/post">
+	<link rel="shortlink" href="https://exa.mp/1">
+	<link rel="prev" href="/post?page=1">
+	<link rel="next" href="/post?page=3">
+	<link rel="manifest" href="/site.webmanifest">
+	<link rel="alternate" hreflang="de" href="/de/post">
+	<link rel="alternate" hreflang="x-default" href="/post">
+	<link rel="alternate" media="only screen and (max-width: 640px)" href="https://m.example.com/post">
+	<link rel="icon" href="/favicon.ico">
+</head>
+</html>
+`
+	base, _ := url.Parse("https://example.com/post?page=2")
+	result, err := parser.Parse(strings.NewReader(linksHtml), parser.WithBaseURL(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := result.Links
+	if links.Canonical != "https://example.com/post" || links.AMP != "https://example.com/amp/post" || links.Shortlink != "https://exa.mp/1" {
+		t.Errorf("canonical, amp or shortlink parsed incorrectly: %+v", links)
+	}
+	if links.Prev != "https://example.com/post?page=1" || links.Next != "https://example.com/post?page=3" {
+		t.Error("prev or next parsed incorrectly")
+	}
+	if links.Manifest != "https://example.com/site.webmanifest" {
+		t.Error("manifest parsed incorrectly")
+	}
+	if len(links.Alternates) != 3 || links.Alternates[2].Media == "" {
+		t.Fatalf("alternates parsed incorrectly: %d", len(links.Alternates))
+	}
+
+	hreflang := links.Hreflang()
+	if len(hreflang) != 2 || hreflang["de"] != "https://example.com/de/post" || hreflang["x-default"] != "https://example.com/post" {
+		t.Errorf("hreflang map built incorrectly: %v", hreflang)
+	}
+
+	if result.Preview().URL != "https://example.com/post" {
+		t.Error("preview must fall back to the canonical link")
+	}
+	if len(result.Favicons) != 1 {
+		t.Error("favicon must still be parsed")
+	}
+}
+
+func TestParserParseScholarly(t *testing.T) {
+	const scholarlyHtml = `
+<html>
+<head>
+	<meta name="DC.title" content="Concurrency in Go">
+	<meta name="DC.creator" content="Doe, Jane">
+	<meta name="dc.Creator" content="Roe, Richard">
+	<meta name="DCTERMS.issued" content="2020-05-17">
+	<meta name="dcterms.subject" content="Programming languages">
+	<meta name="citation_title" content="Concurrency in Go">
+	<meta name="citation_author" content="Doe, Jane">
+	<meta name="citation_author_institution" content="Gopher University">
+	<meta name="citation_author_institution" content="Go Lab">
+	<meta name="citation_author" content="Richard Roe">
+	<meta name="citation_publication_date" content="2020/05/17">
+	<meta name="citation_journal_title" content="Journal of Gophers">
+	<meta name="citation_volume" content="12">
+	<meta name="citation_issue" content="3">
+	<meta name="citation_firstpage" content="101">
+	<meta name="citation_lastpage" content="120">
+	<meta name="citation_doi" content="10.1234/jog.2020.12">
+	<meta name="citation_pdf_url" content="/paper.pdf">
+</head>
+</html>
+`
+	base, _ := url.Parse("https://journal.example.com/article/12")
+	result, err := parser.Parse(strings.NewReader(scholarlyHtml), parser.WithBaseURL(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc := result.DublinCore
+	if dc.Title != "Concurrency in Go" || len(dc.Creators) != 2 || dc.Date != "2020-05-17" || len(dc.Subjects) != 1 {
+		t.Errorf("dublin core parsed incorrectly: %+v", dc)
+	}
+
+	citation := result.Citation
+	if citation.Title != "Concurrency in Go" || len(citation.Authors) != 2 || citation.DOI != "10.1234/jog.2020.12" {
+		t.Fatalf("citation parsed incorrectly: %+v", citation)
+	}
+	if len(citation.Authors[0].Institutions) != 2 || len(citation.Authors[1].Institutions) != 0 {
+		t.Error("citation author institutions must follow their author")
+	}
+	if citation.PDFURL != "https://journal.example.com/paper.pdf" {
+		t.Error("citation pdf url not resolved")
+	}
+
+	bibtex := citation.BibTeX()
+	for _, want := range []string{"@article{doe2020,", "author = {Doe, Jane and Richard Roe}", "journal = {Journal of Gophers}", "pages = {101--120}", "year = {2020}"} {
+		if !strings.Contains(bibtex, want) {
+			t.Errorf("bibtex %q does not contain %q", bibtex, want)
+		}
+	}
+
+	csl, err := citation.CSLJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"type":"article-journal"`, `{"family":"Doe","given":"Jane"}`, `{"family":"Roe","given":"Richard"}`, `"issued":{"date-parts":[[2020,5,17]]}`, `"page":"101-120"`} {
+		if !strings.Contains(string(csl), want) {
+			t.Errorf("csl-json %s does not contain %s", csl, want)
+		}
+	}
+}
+
+func TestParserParseProductBusinessPlace(t *testing.T) {
+	const productHtml = `
+<html>
+<head>
+	<meta property="og:type" content="product">
+	<meta property="product:price:amount" content="19.99">
+	<meta property="product:price:currency" content="USD">
+	<meta property="product:price:amount" content="17.50">
+	<meta property="product:price:currency" content="EUR">
+	<meta property="product:availability" content="in stock">
+	<meta property="product:brand" content="Gopher Co">
+	<meta property="product:retailer_item_id" content="G-42">
+	<meta property="business:contact_data:street_address" content="1 Gopher Way">
+	<meta property="business:contact_data:locality" content="Mountain View">
+	<meta property="business:contact_data:country_name" content="USA">
+	<meta property="place:location:latitude" content="37.4220">
+	<meta property="place:location:longitude" content="-122.0841">
+	<meta property="place:location:altitude" content="not a number">
+</head>
+</html>
+`
+	result, err := parser.Parse(strings.NewReader(productHtml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	product := result.Product
+	if len(product.Prices) != 2 || product.Prices[0].Amount != "19.99" || product.Prices[0].Currency != "USD" ||
+		product.Prices[1].Amount != "17.50" || product.Prices[1].Currency != "EUR" {
+		t.Errorf("product prices parsed incorrectly: %+v", product.Prices)
+	}
+	if product.Availability != "in stock" || product.Brand != "Gopher Co" || product.RetailerItemID != "G-42" {
+		t.Error("product fields parsed incorrectly")
+	}
+
+	contact := result.Business.ContactData
+	if contact.StreetAddress != "1 Gopher Way" || contact.Locality != "Mountain View" || contact.CountryName != "USA" {
+		t.Error("business contact data parsed incorrectly")
+	}
+
+	location := result.Place.Location
+	if location.Latitude != 37.4220 || location.Longitude != -122.0841 || location.Altitude != 0 {
+		t.Errorf("place location parsed incorrectly: %+v", location)
+	}
+}
+
+func TestParserParseAppLinks(t *testing.T) {
+	const appLinksHtml = `
+<html>
+<head>
+	<meta property="al:ios:url" content="gopher://post/42">
+	<meta property="al:ios:app_store_id" content="12345">
+	<meta property="al:ios:app_name" content="Gopher">
+	<meta property="al:ios:url" content="gopherlite://post/42">
+	<meta property="al:ios:app_name" content="Gopher Lite">
+	<meta property="al:android:url" content="gopher://post/42">
+	<meta property="al:android:package" content="com.example.gopher">
+	<meta name="al:windows_universal:url" content="gopher-win://post/42">
+	<meta property="al:web:url" content="/post/42">
+	<meta property="al:web:should_fallback" content="false">
+	<meta name="apple-itunes-app" content="app-id=67890, app-argument=gopher://post/42">
+	<link rel="alternate" href="android-app://com.example.gopher/https/example.com/post/42">
+</head>
+</html>
+`
+	base, _ := url.Parse("https://example.com/")
+	result, err := parser.Parse(strings.NewReader(appLinksHtml), parser.WithBaseURL(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links := result.AppLinks
+	if len(links.Apps) != 6 {
+		t.Fatalf("app links parsed incorrectly: %d apps", len(links.Apps))
+	}
+	if ios := links.Apps[0]; ios.Platform != "ios" || ios.URL != "gopher://post/42" || ios.AppStoreID != "12345" || ios.AppName != "Gopher" {
+		t.Errorf("first ios app parsed incorrectly: %+v", ios)
+	}
+	if lite := links.Apps[1]; lite.Platform != "ios" || lite.URL != "gopherlite://post/42" || lite.AppName != "Gopher Lite" {
+		t.Errorf("repeated ios app parsed incorrectly: %+v", lite)
+	}
+	if windows := links.Lookup("windows_universal"); windows == nil || windows.URL != "gopher-win://post/42" {
+		t.Error("app links declared with name parsed incorrectly")
+	}
+	if links.WebURL != "https://example.com/post/42" || links.ShouldFallback() {
+		t.Error("app links web target parsed incorrectly")
+	}
+
+	var banner, android *parser.AppLink
+	for _, app := range links.Apps {
+		switch app.Source {
+		case parser.AppLinkSourceITunes:
+			banner = app
+		case parser.AppLinkSourceAndroid:
+			android = app
+		}
+	}
+	if banner == nil || banner.Source != parser.AppLinkSourceITunes || banner.AppStoreID != "67890" || banner.URL != "gopher://post/42" {
+		t.Errorf("apple-itunes-app parsed incorrectly: %+v", banner)
+	}
+	if android == nil || android.Package != "com.example.gopher" || android.URL != "https://example.com/post/42" {
+		t.Errorf("android-app link parsed incorrectly: %+v", android)
+	}
+
+	if app := links.Lookup("iphone", "ios"); app != links.Apps[0] {
+		t.Error("lookup must fall back to the next platform")
+	}
+}
+
+func TestParserParseTwitterAppPlatforms(t *testing.T) {
+	const appHtml = `
+<html>
+<head>
+	<meta name="twitter:app:id:googleplay" content="com.example.gopher">
+	<meta name="twitter:app:url:googleplay" content="gopher://post/42">
+	<meta name="twitter:app:id:windows" content="9NBLGGH4R32N">
+	<meta name="twitter:app:country:ipad" content="US">
+</head>
+</html>
+`
+	result, err := parser.Parse(strings.NewReader(appHtml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if app := result.Twitter.Lookup("android", "googleplay"); app == nil || app.ID != "com.example.gopher" || app.URL != "gopher://post/42" {
+		t.Errorf("googleplay app parsed incorrectly: %+v", app)
+	}
+	if app := result.Twitter.Lookup("windows"); app == nil || app.ID != "9NBLGGH4R32N" {
+		t.Error("apps of other platforms must be kept")
+	}
+	if len(result.Twitter.Apps) != 2 {
+		t.Errorf("unknown app fields must not add an app: %+v", result.Twitter.Apps)
+	}
+}
+
+func TestParserParseBodyScan(t *testing.T) {
+	const bodyHtml = `
+<html>
+<head><title>Blog</title></head>
+<body>
+	<header><img src="/logo.png" width="400" height="400"><h1>Gopher Blog</h1></header>
+	<nav><p>Home, archive, about, contact and all the other links of the navigation menu</p></nav>
+	<article>
+		<header><h1>
+			Go 2 is out
+		</h1><time datetime="2030-01-02T10:00:00Z">January 2</time></header>
+		<p>Short intro.</p>
+		<img src="/spacer.gif" width="1" height="1">
+		<img src="/hero.jpg" width="1200px" height="630" alt="Gophers">
+		<p>After years of   design work, the Go team released a new major version of the language today.</p>
+		<video src="/demo.mp4"><source src="/demo.webm" type="video/webm"></video>
+		<audio><source src="/podcast.mp3" type="audio/mpeg"></audio>
+	</article>
+	<script type="application/ld+json">{"@context": "https://schema.org", "@type": "BlogPosting", "headline": "Go 2 is out"}</script>
+</body>
+</html>
+`
+	base, _ := url.Parse("https://blog.example.com/go2")
+
+	result, err := parser.Parse(strings.NewReader(bodyHtml), parser.WithBaseURL(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Heuristics != nil || len(result.JSONLD.Blocks) != 0 {
+		t.Error("the body must only be scanned with WithBodyScan")
+	}
+
+	result, err = parser.Parse(strings.NewReader(bodyHtml), parser.WithBaseURL(base), parser.WithBodyScan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := result.Heuristics
+	if h == nil {
+		t.Fatal("heuristics not collected")
+	}
+	if h.Heading != "Go 2 is out" {
+		t.Errorf("heading guessed incorrectly: %q", h.Heading)
+	}
+	if h.Lead != "After years of design work, the Go team released a new major version of the language today." {
+		t.Errorf("lead guessed incorrectly: %q", h.Lead)
+	}
+	if h.Image == nil || h.Image.URL != "https://blog.example.com/hero.jpg" || h.Image.Width != 1200 || h.Image.Height != 630 {
+		t.Errorf("image guessed incorrectly: %+v", h.Image)
+	}
+	if len(h.Videos) != 2 || h.Videos[1].URL != "https://blog.example.com/demo.webm" || h.Videos[1].Type != "video/webm" {
+		t.Error("video sources collected incorrectly")
+	}
+	if len(h.Audios) != 1 || h.Audios[0].URL != "https://blog.example.com/podcast.mp3" {
+		t.Error("audio sources collected incorrectly")
+	}
+	if len(h.Times) != 1 || h.Times[0] != "2030-01-02T10:00:00Z" {
+		t.Error("time datetime collected incorrectly")
+	}
+	if len(result.JSONLD.Blocks) != 1 || len(result.JSONLD.Articles()) != 1 {
+		t.Error("json-ld of the body not read")
+	}
+
+	preview := result.Preview()
+	if preview.PublishedTime != h.Times[0] || preview.Description != h.Lead || preview.Image == nil || preview.Image.URL != h.Image.URL {
+		t.Errorf("preview must fall back to the heuristics after declared metadata: %+v", preview)
+	}
+
+	// Without a <body> tag the head loop reads every block, some of which end up in the implied body
+	const impliedBodyHtml = `<html><head><script type="application/ld+json">{"@type":"WebSite"}</script></head>` +
+		`<h1>x</h1><script type="application/ld+json">{"@type":"Article"}</script>`
+	result, err = parser.Parse(strings.NewReader(impliedBodyHtml), parser.WithBodyScan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.JSONLD.Blocks) != 2 || len(result.JSONLD.Articles()) != 1 {
+		t.Errorf("json-ld without a body tag read %d times", len(result.JSONLD.Blocks))
+	}
+
+	// 35 tokens end after the heading of the article, before its paragraphs
+	result, err = parser.Parse(strings.NewReader(bodyHtml), parser.WithBodyScan(), parser.WithMaxTokens(35))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = result.Heuristics
+	if !result.Truncated || h == nil || h.Heading != "Go 2 is out" || h.Lead != "" || len(result.JSONLD.Blocks) != 0 {
+		t.Errorf("the token limit must apply to the body: %+v", result.Heuristics)
+	}
+
+	// Reading stops at the token limit instead of going through the rest of a large page
+	large := &countingReader{r: strings.NewReader(bodyHtml + strings.Repeat("<p>filler</p>", 100000))}
+	result, err = parser.Parse(large, parser.WithBodyScan(), parser.WithMaxTokens(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Truncated || large.n > 64<<10 {
+		t.Errorf("%d bytes read past the token limit", large.n)
+	}
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += n
+	return n, err
+}
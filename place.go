@@ -0,0 +1,33 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+type location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude"`
+}
+
+// Place type in Open Graph
+type Place struct {
+	Location location `json:"location"`
+}
+
+func (result *Result) parsePlaceMeta(attrs map[string]string) {
+	// Invalid coordinates are ignored like other numbers
+	f, err := strconv.ParseFloat(strings.TrimSpace(attrs["content"]), 64)
+	if err != nil {
+		return
+	}
+	switch attrs["property"] {
+	case "place:location:latitude":
+		result.Place.Location.Latitude = f
+	case "place:location:longitude":
+		result.Place.Location.Longitude = f
+	case "place:location:altitude":
+		result.Place.Location.Altitude = f
+	}
+}
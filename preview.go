@@ -0,0 +1,295 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Source is a kind of metadata a Preview can be built from
+type Source int
+
+const (
+	// SourceOpenGraph is og:*, article:*, book:* and the other Open Graph namespaces
+	SourceOpenGraph Source = iota
+	// SourceTwitter is twitter:*
+	SourceTwitter
+	// SourceJSONLD is the first Schema.org article, product, recipe or video of the JSON-LD blocks
+	SourceJSONLD
+	// SourceMeta is <title> and the standard <meta name="description"> and <meta name="author">
+	SourceMeta
+	// SourceLink is <link> tags, the icon and the canonical URL
+	SourceLink
+	// SourceHeuristics is what WithBodyScan guessed from the body, the first <h1>, lead paragraph, large image and video
+	SourceHeuristics
+)
+
+// DefaultPrecedence is the order used by Result.Preview, every field is taken
+// from the first source that provides it
+var DefaultPrecedence = []Source{SourceOpenGraph, SourceTwitter, SourceJSONLD, SourceMeta, SourceLink, SourceHeuristics}
+
+// PreviewImage is the image shown in a link preview
+type PreviewImage struct {
+	URL    string `json:"url"`
+	Width  int64  `json:"width"`
+	Height int64  `json:"height"`
+	Alt    string `json:"alt"`
+}
+
+// PreviewPlayer is the media embedded in a link preview
+type PreviewPlayer struct {
+	URL    string `json:"url"`
+	Type   string `json:"type"`
+	Width  int64  `json:"width"`
+	Height int64  `json:"height"`
+}
+
+// Preview is the flattened view of a Result used to unfurl a link
+type Preview struct {
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	URL           string         `json:"url"`
+	SiteName      string         `json:"site_name"`
+	Image         *PreviewImage  `json:"image"`
+	Icon          string         `json:"icon"`
+	Player        *PreviewPlayer `json:"player"`
+	Author        string         `json:"author"`
+	PublishedTime string         `json:"published_time"`
+}
+
+// Preview builds the link preview of the result using DefaultPrecedence
+func (result *Result) Preview() *Preview {
+	return result.PreviewWith(DefaultPrecedence...)
+}
+
+// PreviewWith builds the link preview of the result, every field is taken from
+// the first of sources that provides it. Sources left out are not used.
+func (result *Result) PreviewWith(sources ...Source) *Preview {
+	preview := &Preview{}
+	for _, source := range sources {
+		preview.merge(result.previewFrom(source))
+	}
+	return preview
+}
+
+// merge fills the empty fields of preview with those of other
+func (preview *Preview) merge(other *Preview) {
+	if preview.Title == "" {
+		preview.Title = other.Title
+	}
+	if preview.Description == "" {
+		preview.Description = other.Description
+	}
+	if preview.URL == "" {
+		preview.URL = other.URL
+	}
+	if preview.SiteName == "" {
+		preview.SiteName = other.SiteName
+	}
+	if preview.Image == nil {
+		preview.Image = other.Image
+	}
+	if preview.Icon == "" {
+		preview.Icon = other.Icon
+	}
+	if preview.Player == nil {
+		preview.Player = other.Player
+	}
+	if preview.Author == "" {
+		preview.Author = other.Author
+	}
+	if preview.PublishedTime == "" {
+		preview.PublishedTime = other.PublishedTime
+	}
+}
+
+func (result *Result) previewFrom(source Source) *Preview {
+	switch source {
+	case SourceOpenGraph:
+		return result.openGraphPreview()
+	case SourceTwitter:
+		return result.twitterPreview()
+	case SourceJSONLD:
+		return result.jsonldPreview()
+	case SourceMeta:
+		return &Preview{
+			Title:       result.Title,
+			Description: result.Description,
+			Author:      result.Author,
+		}
+	case SourceLink:
+		return &Preview{
+			URL:  result.Links.Canonical,
+			Icon: result.largestFavicon(),
+		}
+	case SourceHeuristics:
+		return result.heuristicsPreview()
+	}
+	return &Preview{}
+}
+
+func (result *Result) openGraphPreview() *Preview {
+	preview := &Preview{
+		Title:         result.OpenGraph.Title,
+		Description:   result.OpenGraph.Description,
+		URL:           result.OpenGraph.URL,
+		SiteName:      result.OpenGraph.SiteName,
+		PublishedTime: result.Article.PublishedTime,
+	}
+	if len(result.Article.Authors) > 0 {
+		preview.Author = result.Article.Authors[0]
+	} else if len(result.Book.Authors) > 0 {
+		preview.Author = result.Book.Authors[0]
+	}
+	for _, image := range result.Images {
+		if u := firstNonEmpty(image.SecureURL, image.URL); u != "" {
+			preview.Image = &PreviewImage{URL: u, Width: image.Width, Height: image.Height, Alt: image.Alt}
+			break
+		}
+	}
+	for _, video := range result.Videos {
+		if u := firstNonEmpty(video.SecureURL, video.URL); u != "" {
+			preview.Player = &PreviewPlayer{URL: u, Type: video.Type, Width: video.Width, Height: video.Height}
+			break
+		}
+	}
+	return preview
+}
+
+// twitterPreview leaves twitter:site and twitter:creator out, they are handles such as @nytimes
+// and not a site or author name
+func (result *Result) twitterPreview() *Preview {
+	preview := &Preview{
+		Title:       result.Twitter.Title,
+		Description: result.Twitter.Description,
+	}
+	if result.Twitter.Image != "" {
+		preview.Image = &PreviewImage{URL: result.Twitter.Image, Alt: result.Twitter.ImageAlt}
+	}
+	if result.Twitter.Player.URL != "" {
+		preview.Player = &PreviewPlayer{
+			URL:    result.Twitter.Player.URL,
+			Width:  result.Twitter.Player.Width,
+			Height: result.Twitter.Player.Height,
+		}
+	}
+	return preview
+}
+
+func (result *Result) heuristicsPreview() *Preview {
+	h := result.Heuristics
+	if h == nil {
+		return &Preview{}
+	}
+	preview := &Preview{
+		Title:       h.Heading,
+		Description: h.Lead,
+	}
+	if h.Image != nil {
+		preview.Image = &PreviewImage{URL: h.Image.URL, Width: h.Image.Width, Height: h.Image.Height, Alt: h.Image.Alt}
+	}
+	if len(h.Videos) > 0 {
+		preview.Player = &PreviewPlayer{URL: h.Videos[0].URL, Type: h.Videos[0].Type}
+	}
+	if len(h.Times) > 0 {
+		preview.PublishedTime = h.Times[0]
+	}
+	return preview
+}
+
+func (result *Result) jsonldPreview() *Preview {
+	preview := &Preview{}
+	ld := &result.JSONLD
+	if articles := ld.Articles(); len(articles) > 0 {
+		article := articles[0]
+		preview.Title = article.Headline
+		preview.Description = article.Description
+		preview.URL = article.URL
+		preview.PublishedTime = article.DatePublished
+		if len(article.Images) > 0 {
+			preview.Image = &PreviewImage{URL: article.Images[0]}
+		}
+		if len(article.Authors) > 0 {
+			preview.Author = article.Authors[0].Name
+		}
+		if article.Publisher != nil {
+			preview.SiteName = article.Publisher.Name
+		}
+	} else if products := ld.Products(); len(products) > 0 {
+		product := products[0]
+		preview.Title = product.Name
+		preview.Description = product.Description
+		preview.URL = product.URL
+		if len(product.Images) > 0 {
+			preview.Image = &PreviewImage{URL: product.Images[0]}
+		}
+	} else if recipes := ld.Recipes(); len(recipes) > 0 {
+		recipe := recipes[0]
+		preview.Title = recipe.Name
+		preview.Description = recipe.Description
+		preview.URL = recipe.URL
+		preview.PublishedTime = recipe.DatePublished
+		if len(recipe.Images) > 0 {
+			preview.Image = &PreviewImage{URL: recipe.Images[0]}
+		}
+		if len(recipe.Authors) > 0 {
+			preview.Author = recipe.Authors[0].Name
+		}
+	}
+
+	if videos := ld.VideoObjects(); len(videos) > 0 {
+		video := videos[0]
+		setOnce(&preview.Title, video.Name)
+		setOnce(&preview.Description, video.Description)
+		setOnce(&preview.PublishedTime, video.UploadDate)
+		if preview.Image == nil && len(video.ThumbnailURLs) > 0 {
+			preview.Image = &PreviewImage{URL: video.ThumbnailURLs[0]}
+		}
+		if u := firstNonEmpty(video.EmbedURL, video.ContentURL); u != "" {
+			preview.Player = &PreviewPlayer{URL: u}
+		}
+	}
+	if organizations := ld.Organizations(); preview.SiteName == "" && len(organizations) > 0 {
+		preview.SiteName = organizations[0].Name
+	}
+	return preview
+}
+
+// largestFavicon returns the favicon with the largest declared size, or the first one
+func (result *Result) largestFavicon() string {
+	icon, largest := "", int64(-1)
+	for _, favicon := range result.Favicons {
+		if favicon.URL == "" {
+			continue
+		}
+		if size := faviconSize(favicon.Sizes); size > largest {
+			icon, largest = favicon.URL, size
+		}
+	}
+	return icon
+}
+
+// faviconSize returns the width of the largest entry of a sizes attribute such as "16x16 32x32",
+// "any" counts as the largest possible
+func faviconSize(sizes string) int64 {
+	largest := int64(0)
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		if size == "any" {
+			return 1 << 16
+		}
+		if i := strings.IndexByte(size, 'x'); i > 0 {
+			if w, err := strconv.ParseInt(size[:i], 10, 64); err == nil && w > largest {
+				largest = w
+			}
+		}
+	}
+	return largest
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
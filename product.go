@@ -0,0 +1,65 @@
+package parser
+
+// Price is an amount in a currency, such as 9.99 and USD
+type Price struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// Product type in Open Graph
+type Product struct {
+	// Prices holds every product:price, in several currencies for some shops
+	Prices         []*Price `json:"prices"`
+	OriginalPrices []*Price `json:"original_prices"`
+	SalePrices     []*Price `json:"sale_prices"`
+	Availability   string   `json:"availability"`
+	Condition      string   `json:"condition"`
+	Brand          string   `json:"brand"`
+	RetailerItemID string   `json:"retailer_item_id"`
+	Category       string   `json:"category"`
+}
+
+// addPriceAmount starts a new price, unless the last one is waiting for its amount
+func addPriceAmount(prices []*Price, amount string) []*Price {
+	if len(prices) > 0 && prices[len(prices)-1].Amount == "" {
+		prices[len(prices)-1].Amount = amount
+		return prices
+	}
+	return append(prices, &Price{Amount: amount})
+}
+
+// addPriceCurrency sets the currency of the last price, unless it already has one
+func addPriceCurrency(prices []*Price, currency string) []*Price {
+	if len(prices) > 0 && prices[len(prices)-1].Currency == "" {
+		prices[len(prices)-1].Currency = currency
+		return prices
+	}
+	return append(prices, &Price{Currency: currency})
+}
+
+func (result *Result) parseProductMeta(attrs map[string]string) {
+	switch attrs["property"] {
+	case "product:price:amount", "og:price:amount":
+		result.Product.Prices = addPriceAmount(result.Product.Prices, attrs["content"])
+	case "product:price:currency", "og:price:currency":
+		result.Product.Prices = addPriceCurrency(result.Product.Prices, attrs["content"])
+	case "product:original_price:amount":
+		result.Product.OriginalPrices = addPriceAmount(result.Product.OriginalPrices, attrs["content"])
+	case "product:original_price:currency":
+		result.Product.OriginalPrices = addPriceCurrency(result.Product.OriginalPrices, attrs["content"])
+	case "product:sale_price:amount":
+		result.Product.SalePrices = addPriceAmount(result.Product.SalePrices, attrs["content"])
+	case "product:sale_price:currency":
+		result.Product.SalePrices = addPriceCurrency(result.Product.SalePrices, attrs["content"])
+	case "product:availability", "og:availability":
+		result.Product.Availability = attrs["content"]
+	case "product:condition":
+		result.Product.Condition = attrs["content"]
+	case "product:brand", "og:brand":
+		result.Product.Brand = attrs["content"]
+	case "product:retailer_item_id":
+		result.Product.RetailerItemID = attrs["content"]
+	case "product:category":
+		result.Product.Category = attrs["content"]
+	}
+}
@@ -8,15 +8,15 @@ type Profile struct {
 	Gender    string `json:"gender"`
 }
 
-func (p *Parser) parseProfileMeta(attrs map[string]string) {
+func (result *Result) parseProfileMeta(attrs map[string]string) {
 	switch attrs["property"] {
 	case "profile:first_name":
-		p.Profile.FirstName = attrs["content"]
+		result.Profile.FirstName = attrs["content"]
 	case "profile:last_name":
-		p.Profile.LastName = attrs["content"]
+		result.Profile.LastName = attrs["content"]
 	case "profile:username":
-		p.Profile.Username = attrs["content"]
+		result.Profile.Username = attrs["content"]
 	case "profile:gender":
-		p.Profile.Gender = attrs["content"]
+		result.Profile.Gender = attrs["content"]
 	}
 }
@@ -0,0 +1,243 @@
+package parser
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Namespaces of the Open Graph vocabularies and the prefix ParseMetaProperty knows them by
+var ogVocabularies = map[string]string{
+	"http://ogp.me/ns#":           "og",
+	"http://ogp.me/ns/music#":     "music",
+	"http://ogp.me/ns/video#":     "video",
+	"http://ogp.me/ns/article#":   "article",
+	"http://ogp.me/ns/book#":      "book",
+	"http://ogp.me/ns/profile#":   "profile",
+	"http://ogp.me/ns/product#":   "product",
+	"http://ogp.me/ns/business#":  "business",
+	"http://ogp.me/ns/place#":     "place",
+	"https://ogp.me/ns#":          "og",
+	"https://ogp.me/ns/music#":    "music",
+	"https://ogp.me/ns/video#":    "video",
+	"https://ogp.me/ns/article#":  "article",
+	"https://ogp.me/ns/book#":     "book",
+	"https://ogp.me/ns/profile#":  "profile",
+	"https://ogp.me/ns/product#":  "product",
+	"https://ogp.me/ns/business#": "business",
+	"https://ogp.me/ns/place#":    "place",
+}
+
+// Prefixes of the RDFa initial context that are common in web pages
+var rdfaInitialContext = map[string]string{
+	"og":      "http://ogp.me/ns#",
+	"schema":  "http://schema.org/",
+	"dc":      "http://purl.org/dc/terms/",
+	"dcterms": "http://purl.org/dc/terms/",
+	"foaf":    "http://xmlns.com/foaf/0.1/",
+	"rdf":     "http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+	"rdfs":    "http://www.w3.org/2000/01/rdf-schema#",
+	"xsd":     "http://www.w3.org/2001/XMLSchema#",
+}
+
+// parsePrefixes reads a prefix attribute such as "ogp: http://ogp.me/ns# fb: http://ogp.me/ns/fb#"
+// into prefixes, which is copied first so that declarations stay scoped to their element
+func parsePrefixes(prefixes map[string]string, attr string) map[string]string {
+	declared := make(map[string]string, len(prefixes))
+	for prefix, iri := range prefixes {
+		declared[prefix] = iri
+	}
+	fields := strings.Fields(attr)
+	for i := 0; i+1 < len(fields); i += 2 {
+		if !strings.HasSuffix(fields[i], ":") {
+			// Not a "prefix: iri" pair, resynchronise on the next field
+			i--
+			continue
+		}
+		declared[strings.ToLower(strings.TrimSuffix(fields[i], ":"))] = fields[i+1]
+	}
+	return declared
+}
+
+// normalizeProperty maps a property using a declared prefix, such as ogp:title, or a full IRI,
+// such as http://ogp.me/ns#title, to the og:title form matched by ParseMetaProperty
+func normalizeProperty(property string, prefixes map[string]string) string {
+	iri := property
+	if i := strings.IndexByte(property, ':'); i > 0 && !strings.HasPrefix(property[i:], "://") {
+		namespace, ok := prefixes[strings.ToLower(property[:i])]
+		if !ok {
+			return property
+		}
+		iri = namespace + property[i+1:]
+	}
+
+	// The longest namespace wins so that og does not swallow og/music
+	best := ""
+	for namespace := range ogVocabularies {
+		if strings.HasPrefix(iri, namespace) && len(namespace) > len(best) {
+			best = namespace
+		}
+	}
+	if best == "" {
+		return property
+	}
+	return ogVocabularies[best] + ":" + iri[len(best):]
+}
+
+// RDFaItem is a resource described with RDFa Lite, types and property names are expanded to IRIs
+type RDFaItem struct {
+	Types      []string                `json:"type,omitempty"`
+	Resource   string                  `json:"resource,omitempty"`
+	Properties map[string][]*RDFaValue `json:"properties"`
+}
+
+// RDFaValue is the value of a property, either text or a nested item
+type RDFaValue struct {
+	Text string
+	Item *RDFaItem
+}
+
+// MarshalJSON writes the value as a string or an item
+func (v *RDFaValue) MarshalJSON() ([]byte, error) {
+	if v.Item != nil {
+		return json.Marshal(v.Item)
+	}
+	return json.Marshal(v.Text)
+}
+
+// Text returns the first text value of the property with the given IRI
+func (item *RDFaItem) Text(iri string) string {
+	for _, value := range item.Properties[iri] {
+		if value.Item == nil {
+			return value.Text
+		}
+	}
+	return ""
+}
+
+func (item *RDFaItem) add(property string, value *RDFaValue) {
+	if item.Properties == nil {
+		item.Properties = make(map[string][]*RDFaValue)
+	}
+	item.Properties[property] = append(item.Properties[property], value)
+}
+
+// rdfaContext is what an element inherits from its ancestors
+type rdfaContext struct {
+	vocab    string
+	prefixes map[string]string
+	subject  *RDFaItem
+}
+
+type rdfaParser struct {
+	base *url.URL
+	// The document itself, subject of the properties outside of any typeof
+	document *RDFaItem
+	items    []*RDFaItem
+}
+
+// parseRDFa collects the RDFa Lite (vocab, prefix, typeof, property, resource) items of doc.
+// Properties outside of any typeof describe the document, which then comes first.
+func (result *Result) parseRDFa(doc *html.Node) {
+	r := &rdfaParser{
+		document: &RDFaItem{Resource: result.BaseURL},
+	}
+	if base, err := url.Parse(result.BaseURL); err == nil && base.IsAbs() {
+		r.base = base
+	}
+
+	r.walk(doc, rdfaContext{prefixes: rdfaInitialContext, subject: r.document})
+
+	if len(r.document.Properties) > 0 {
+		result.RDFa = append(result.RDFa, r.document)
+	}
+	result.RDFa = append(result.RDFa, r.items...)
+}
+
+func (r *rdfaParser) walk(n *html.Node, ctx rdfaContext) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		r.walk(c, r.element(c, ctx))
+	}
+}
+
+// element records what n says and returns the context of its children
+func (r *rdfaParser) element(n *html.Node, ctx rdfaContext) rdfaContext {
+	if vocab, ok := getAttr(n, "vocab"); ok {
+		ctx.vocab = strings.TrimSpace(vocab)
+	}
+	if prefix, ok := getAttr(n, "prefix"); ok {
+		ctx.prefixes = parsePrefixes(ctx.prefixes, prefix)
+	}
+	property, hasProperty := getAttr(n, "property")
+
+	if typeof, ok := getAttr(n, "typeof"); ok {
+		item := &RDFaItem{}
+		for _, term := range strings.Fields(typeof) {
+			item.Types = append(item.Types, r.expand(term, ctx))
+		}
+		if resource, ok := getAttr(n, "resource"); ok {
+			item.Resource = resolveReference(r.base, resource)
+		}
+		if hasProperty {
+			for _, term := range strings.Fields(property) {
+				ctx.subject.add(r.expand(term, ctx), &RDFaValue{Item: item})
+			}
+		} else {
+			r.items = append(r.items, item)
+		}
+		ctx.subject = item
+		return ctx
+	}
+
+	if hasProperty {
+		value := &RDFaValue{Text: r.value(n)}
+		for _, term := range strings.Fields(property) {
+			ctx.subject.add(r.expand(term, ctx), value)
+		}
+	}
+	return ctx
+}
+
+// value returns the content, resource, link or text of a property element
+func (r *rdfaParser) value(n *html.Node) string {
+	if content, ok := getAttr(n, "content"); ok {
+		return content
+	}
+	if resource, ok := getAttr(n, "resource"); ok {
+		return resolveReference(r.base, resource)
+	}
+	for _, key := range []string{"href", "src"} {
+		if value, ok := getAttr(n, key); ok {
+			return resolveReference(r.base, value)
+		}
+	}
+	if n.DataAtom == atom.Time {
+		if datetime, ok := getAttr(n, "datetime"); ok {
+			return datetime
+		}
+	}
+	return textContent(n)
+}
+
+// expand turns a term, prefixed name or IRI into an IRI
+func (r *rdfaParser) expand(term string, ctx rdfaContext) string {
+	if strings.Contains(term, "://") {
+		return term
+	}
+	if i := strings.IndexByte(term, ':'); i > 0 {
+		if namespace, ok := ctx.prefixes[strings.ToLower(term[:i])]; ok {
+			return namespace + term[i+1:]
+		}
+		return term
+	}
+	if ctx.vocab != "" {
+		return ctx.vocab + term
+	}
+	return term
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+)
+
+// resolveURLs makes the URLs of the result absolute. Relative references are resolved against
+// <base href>, itself resolved against the document URL, values that change are kept in RawURLs.
+func (result *Result) resolveURLs(documentURL *url.URL, baseHref string) {
+	base := documentURL
+	if ref, err := url.Parse(strings.TrimSpace(baseHref)); err == nil && baseHref != "" {
+		if base != nil {
+			base = base.ResolveReference(ref)
+		} else {
+			base = ref
+		}
+	}
+	if base == nil || !base.IsAbs() {
+		return
+	}
+	result.BaseURL = base.String()
+
+	result.resolveURL(base, &result.OpenGraph.URL)
+	for _, image := range result.Images {
+		result.resolveURL(base, &image.URL)
+		result.resolveURL(base, &image.SecureURL)
+	}
+	for _, video := range result.Videos {
+		result.resolveURL(base, &video.URL)
+		result.resolveURL(base, &video.SecureURL)
+	}
+	for _, audio := range result.Audios {
+		result.resolveURL(base, &audio.URL)
+		result.resolveURL(base, &audio.SecureURL)
+	}
+	for _, favicon := range result.Favicons {
+		result.resolveURL(base, &favicon.URL)
+	}
+	for _, link := range []*string{&result.Links.Canonical, &result.Links.AMP, &result.Links.Shortlink,
+		&result.Links.Prev, &result.Links.Next, &result.Links.Manifest} {
+		result.resolveURL(base, link)
+	}
+	for _, alternate := range result.Links.Alternates {
+		result.resolveURL(base, &alternate.URL)
+	}
+	result.resolveURL(base, &result.Citation.PDFURL)
+	result.resolveURL(base, &result.Citation.AbstractHTMLURL)
+	result.resolveURL(base, &result.Citation.FulltextHTMLURL)
+	for _, feed := range result.Feeds {
+		result.resolveURL(base, &feed.URL)
+	}
+	for _, link := range result.OEmbedLinks {
+		result.resolveURL(base, &link.URL)
+	}
+	result.resolveURL(base, &result.AppLinks.WebURL)
+	result.resolveURL(base, &result.Twitter.Image)
+	result.resolveURL(base, &result.Twitter.Player.URL)
+	result.resolveURL(base, &result.Twitter.Player.Stream)
+}
+
+// resolveReference resolves value against base, leaving it alone when either is unusable
+func resolveReference(base *url.URL, value string) string {
+	if base == nil || value == "" {
+		return value
+	}
+	ref, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return value
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func (result *Result) resolveURL(base *url.URL, value *string) {
+	resolved := resolveReference(base, *value)
+	if resolved == *value {
+		return
+	}
+	if result.RawURLs == nil {
+		result.RawURLs = make(map[string]string)
+	}
+	result.RawURLs[resolved] = *value
+	*value = resolved
+}
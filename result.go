@@ -3,8 +3,18 @@ package parser
 type Result struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	Author      string `json:"author"`
+
+	// Charset is the encoding the document was decoded from, e.g. "shift_jis" or "windows-1252"
+	Charset string `json:"charset"`
+	// Truncated is set when a size, token or time limit stopped parsing before the end of the head
+	Truncated bool `json:"truncated"`
 
 	OpenGraph OG `json:"open_graph"`
+	// Microdata holds the top-level items when WithMicrodata is set
+	Microdata []*MicrodataItem `json:"microdata,omitempty"`
+	// RDFa holds the document and the top-level typed resources when WithRDFa is set
+	RDFa []*RDFaItem `json:"rdfa,omitempty"`
 
 	Images []*Image `json:"images"`
 	Videos []*Video `json:"videos"`
@@ -16,10 +26,42 @@ type Result struct {
 	Book    Book    `json:"book"`
 	Profile Profile `json:"profile"`
 
+	Product  Product  `json:"product"`
+	Business Business `json:"business"`
+	Place    Place    `json:"place"`
+
 	Favicons []*Favicon `json:"favicons"`
+	// Links holds canonical, alternate, AMP and the other <link> relations
+	Links Links `json:"links"`
+	// Manifest is the Web App Manifest of the page when WithManifest is set
+	Manifest *Manifest `json:"manifest,omitempty"`
+	// Feeds are the RSS, Atom and JSON feeds advertised by the page
+	Feeds []*FeedLink `json:"feeds,omitempty"`
+
+	// OEmbedLinks are the oEmbed endpoints advertised by the page
+	OEmbedLinks []*OEmbedLink `json:"oembed_links,omitempty"`
+	// OEmbed is the response of the page's oEmbed endpoint when WithOEmbed is set
+	OEmbed *OEmbed `json:"oembed,omitempty"`
 
 	// Twitter
 	Twitter Twitter `json:"twitter"`
+	// AppLinks holds the native apps of App Links, the iOS smart banner and android-app:// alternates
+	AppLinks AppLinks `json:"app_links"`
+
+	JSONLD JSONLD `json:"json_ld"`
+	// Heuristics holds the fallbacks guessed from the body when WithBodyScan is set, not declared metadata
+	Heuristics *Heuristics `json:"heuristics,omitempty"`
+
+	// Scholarly metadata
+	DublinCore DublinCore `json:"dublin_core"`
+	Citation   Citation   `json:"citation"`
+
+	// BaseURL is the absolute URL that relative URLs were resolved against
+	BaseURL string `json:"base_url"`
+	// RawURLs maps resolved URLs to the value written in the document, for those that changed.
+	// When several values resolve to the same URL, such as icon.png and ./icon.png, only the
+	// value of the field resolved last is kept.
+	RawURLs map[string]string `json:"raw_urls,omitempty"`
 }
 
 // GetTitle returns either Open Graph title or standard title as fallback
@@ -39,3 +81,12 @@ func (result *Result) GetDescription() string {
 		return result.Description
 	}
 }
+
+// RawURL returns the value the document used for the resolved URL u, the last one resolved
+// when several values resolve to u
+func (result *Result) RawURL(u string) string {
+	if raw, ok := result.RawURLs[u]; ok {
+		return raw
+	}
+	return u
+}
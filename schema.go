@@ -0,0 +1,312 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SchemaEntity is a person, organization or brand referenced from another Schema.org node
+type SchemaEntity struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// SchemaArticle is a Schema.org Article, NewsArticle or BlogPosting
+type SchemaArticle struct {
+	Type          string          `json:"type"`
+	Headline      string          `json:"headline"`
+	Description   string          `json:"description"`
+	URL           string          `json:"url"`
+	Images        []string        `json:"images"`
+	Authors       []*SchemaEntity `json:"authors"`
+	Publisher     *SchemaEntity   `json:"publisher"`
+	DatePublished string          `json:"date_published"`
+	DateModified  string          `json:"date_modified"`
+	Section       string          `json:"section"`
+	Keywords      []string        `json:"keywords"`
+}
+
+// SchemaOffer is a Schema.org Offer or AggregateOffer
+type SchemaOffer struct {
+	Price         string `json:"price"`
+	LowPrice      string `json:"low_price"`
+	HighPrice     string `json:"high_price"`
+	PriceCurrency string `json:"price_currency"`
+	Availability  string `json:"availability"`
+	URL           string `json:"url"`
+}
+
+// SchemaRating is a Schema.org AggregateRating
+type SchemaRating struct {
+	Value string `json:"value"`
+	Count string `json:"count"`
+}
+
+// SchemaProduct is a Schema.org Product
+type SchemaProduct struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	URL         string         `json:"url"`
+	Images      []string       `json:"images"`
+	Brand       *SchemaEntity  `json:"brand"`
+	SKU         string         `json:"sku"`
+	GTIN        string         `json:"gtin"`
+	Offers      []*SchemaOffer `json:"offers"`
+	Rating      *SchemaRating  `json:"rating"`
+}
+
+// SchemaRecipe is a Schema.org Recipe
+type SchemaRecipe struct {
+	Name          string          `json:"name"`
+	Description   string          `json:"description"`
+	URL           string          `json:"url"`
+	Images        []string        `json:"images"`
+	Authors       []*SchemaEntity `json:"authors"`
+	DatePublished string          `json:"date_published"`
+	PrepTime      string          `json:"prep_time"`
+	CookTime      string          `json:"cook_time"`
+	TotalTime     string          `json:"total_time"`
+	Yield         string          `json:"yield"`
+	Ingredients   []string        `json:"ingredients"`
+	Instructions  []string        `json:"instructions"`
+	Rating        *SchemaRating   `json:"rating"`
+}
+
+// SchemaVideoObject is a Schema.org VideoObject
+type SchemaVideoObject struct {
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	ThumbnailURLs []string `json:"thumbnail_urls"`
+	UploadDate    string   `json:"upload_date"`
+	Duration      string   `json:"duration"`
+	ContentURL    string   `json:"content_url"`
+	EmbedURL      string   `json:"embed_url"`
+}
+
+// SchemaOrganization is a Schema.org Organization
+type SchemaOrganization struct {
+	Type   string   `json:"type"`
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Logo   string   `json:"logo"`
+	SameAs []string `json:"same_as"`
+}
+
+// SchemaListItem is an entry of a Schema.org BreadcrumbList
+type SchemaListItem struct {
+	Position int64  `json:"position"`
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+}
+
+// SchemaBreadcrumbList is a Schema.org BreadcrumbList
+type SchemaBreadcrumbList struct {
+	Items []*SchemaListItem `json:"items"`
+}
+
+// Articles returns the Article, NewsArticle and BlogPosting nodes
+func (ld *JSONLD) Articles() []*SchemaArticle {
+	var articles []*SchemaArticle
+	for _, node := range ld.NodesOfType("Article", "NewsArticle", "BlogPosting") {
+		articles = append(articles, &SchemaArticle{
+			Type:          schemaTypeName(ldText(node, "@type")),
+			Headline:      ldText(node, "headline"),
+			Description:   ldText(node, "description"),
+			URL:           ldURL(node, "url"),
+			Images:        ldURLs(node, "image"),
+			Authors:       schemaEntities(node, "author"),
+			Publisher:     schemaEntity(node, "publisher"),
+			DatePublished: ldText(node, "datePublished"),
+			DateModified:  ldText(node, "dateModified"),
+			Section:       ldText(node, "articleSection"),
+			Keywords:      schemaKeywords(node),
+		})
+	}
+	return articles
+}
+
+// Products returns the Product nodes
+func (ld *JSONLD) Products() []*SchemaProduct {
+	var products []*SchemaProduct
+	for _, node := range ld.NodesOfType("Product") {
+		product := &SchemaProduct{
+			Name:        ldText(node, "name"),
+			Description: ldText(node, "description"),
+			URL:         ldURL(node, "url"),
+			Images:      ldURLs(node, "image"),
+			Brand:       schemaEntity(node, "brand"),
+			SKU:         ldText(node, "sku"),
+			GTIN:        schemaGTIN(node),
+			Rating:      schemaRating(node),
+		}
+		for _, offer := range ldObjects(node, "offers") {
+			product.Offers = append(product.Offers, &SchemaOffer{
+				Price:         ldText(offer, "price"),
+				LowPrice:      ldText(offer, "lowPrice"),
+				HighPrice:     ldText(offer, "highPrice"),
+				PriceCurrency: ldText(offer, "priceCurrency"),
+				Availability:  schemaTypeName(ldText(offer, "availability")),
+				URL:           ldURL(offer, "url"),
+			})
+		}
+		products = append(products, product)
+	}
+	return products
+}
+
+// Recipes returns the Recipe nodes
+func (ld *JSONLD) Recipes() []*SchemaRecipe {
+	var recipes []*SchemaRecipe
+	for _, node := range ld.NodesOfType("Recipe") {
+		recipes = append(recipes, &SchemaRecipe{
+			Name:          ldText(node, "name"),
+			Description:   ldText(node, "description"),
+			URL:           ldURL(node, "url"),
+			Images:        ldURLs(node, "image"),
+			Authors:       schemaEntities(node, "author"),
+			DatePublished: ldText(node, "datePublished"),
+			PrepTime:      ldText(node, "prepTime"),
+			CookTime:      ldText(node, "cookTime"),
+			TotalTime:     ldText(node, "totalTime"),
+			Yield:         ldText(node, "recipeYield"),
+			Ingredients:   ldTexts(node, "recipeIngredient"),
+			Instructions:  schemaInstructions(node),
+			Rating:        schemaRating(node),
+		})
+	}
+	return recipes
+}
+
+// VideoObjects returns the VideoObject nodes
+func (ld *JSONLD) VideoObjects() []*SchemaVideoObject {
+	var videos []*SchemaVideoObject
+	for _, node := range ld.NodesOfType("VideoObject") {
+		videos = append(videos, &SchemaVideoObject{
+			Name:          ldText(node, "name"),
+			Description:   ldText(node, "description"),
+			ThumbnailURLs: ldURLs(node, "thumbnailUrl"),
+			UploadDate:    ldText(node, "uploadDate"),
+			Duration:      ldText(node, "duration"),
+			ContentURL:    ldURL(node, "contentUrl"),
+			EmbedURL:      ldURL(node, "embedUrl"),
+		})
+	}
+	return videos
+}
+
+// Organizations returns the Organization nodes, including NewsMediaOrganization and Corporation
+func (ld *JSONLD) Organizations() []*SchemaOrganization {
+	var organizations []*SchemaOrganization
+	for _, node := range ld.NodesOfType("Organization", "NewsMediaOrganization", "Corporation") {
+		organizations = append(organizations, &SchemaOrganization{
+			Type:   schemaTypeName(ldText(node, "@type")),
+			Name:   ldText(node, "name"),
+			URL:    ldURL(node, "url"),
+			Logo:   ldURL(node, "logo"),
+			SameAs: ldURLs(node, "sameAs"),
+		})
+	}
+	return organizations
+}
+
+// BreadcrumbLists returns the BreadcrumbList nodes
+func (ld *JSONLD) BreadcrumbLists() []*SchemaBreadcrumbList {
+	var lists []*SchemaBreadcrumbList
+	for _, node := range ld.NodesOfType("BreadcrumbList") {
+		list := &SchemaBreadcrumbList{}
+		for _, element := range ldObjects(node, "itemListElement") {
+			item := &SchemaListItem{
+				Name: ldText(element, "name"),
+				URL:  ldURL(element, "item"),
+			}
+			if position, err := strconv.ParseInt(ldText(element, "position"), 10, 64); err == nil {
+				item.Position = position
+			}
+			// The name is often given on the item itself
+			if item.Name == "" {
+				item.Name = ldText(element, "item")
+				if item.Name == item.URL {
+					item.Name = ""
+				}
+			}
+			list.Items = append(list.Items, item)
+		}
+		lists = append(lists, list)
+	}
+	return lists
+}
+
+func schemaEntities(node map[string]interface{}, key string) []*SchemaEntity {
+	var entities []*SchemaEntity
+	for _, object := range ldObjects(node, key) {
+		entities = append(entities, &SchemaEntity{
+			Type: schemaTypeName(ldText(object, "@type")),
+			Name: ldText(object, "name"),
+			URL:  ldURL(object, "url"),
+		})
+	}
+	return entities
+}
+
+func schemaEntity(node map[string]interface{}, key string) *SchemaEntity {
+	entities := schemaEntities(node, key)
+	if len(entities) == 0 {
+		return nil
+	}
+	return entities[0]
+}
+
+func schemaRating(node map[string]interface{}) *SchemaRating {
+	objects := ldObjects(node, "aggregateRating")
+	if len(objects) == 0 {
+		return nil
+	}
+	rating := &SchemaRating{
+		Value: ldText(objects[0], "ratingValue"),
+		Count: ldText(objects[0], "ratingCount"),
+	}
+	if rating.Count == "" {
+		rating.Count = ldText(objects[0], "reviewCount")
+	}
+	return rating
+}
+
+func schemaGTIN(node map[string]interface{}) string {
+	for _, key := range []string{"gtin", "gtin13", "gtin12", "gtin14", "gtin8"} {
+		if gtin := ldText(node, key); gtin != "" {
+			return gtin
+		}
+	}
+	return ""
+}
+
+// schemaKeywords accepts both a comma separated string and an array
+func schemaKeywords(node map[string]interface{}) []string {
+	var keywords []string
+	for _, text := range ldTexts(node, "keywords") {
+		for _, keyword := range strings.Split(text, ",") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				keywords = append(keywords, keyword)
+			}
+		}
+	}
+	return keywords
+}
+
+// schemaInstructions flattens plain text, HowToStep and HowToSection instructions
+func schemaInstructions(node map[string]interface{}) []string {
+	var instructions []string
+	for _, step := range ldObjects(node, "recipeInstructions") {
+		if _, ok := step["itemListElement"]; ok {
+			instructions = append(instructions, schemaInstructions(map[string]interface{}{
+				"recipeInstructions": step["itemListElement"],
+			})...)
+		} else if text := ldText(step, "text"); text != "" {
+			instructions = append(instructions, text)
+		} else if name := ldText(step, "name"); name != "" {
+			instructions = append(instructions, name)
+		}
+	}
+	return instructions
+}
@@ -0,0 +1,233 @@
+// Package server exposes the parser as an HTTP unfurl service.
+//
+// Endpoints:
+//
+//	GET  /unfurl?url=...[&view=preview]  parses one page
+//	POST /unfurl                          parses a batch, body {"urls": [...], "view": "preview"}
+//	GET  /healthz                         reports the service is up
+//
+// Failures are answered with {"error": {"code": "...", "message": "..."}}.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	parser "github.com/ammit/go-metaparser"
+)
+
+const (
+	defaultMaxConcurrency = 16
+	defaultTimeout        = 10 * time.Second
+	defaultMaxBatch       = 20
+	maxRequestBodyBytes   = 1 << 20
+)
+
+// Config configures a Server, zero values select the defaults
+type Config struct {
+	// Options configure the parser used for every page
+	Options []parser.Option
+	// MaxConcurrency is the number of pages fetched at the same time, 16 by default
+	MaxConcurrency int
+	// Timeout is the time allowed for a single page, waiting for a fetch slot included, 10 seconds by default
+	Timeout time.Duration
+	// MaxBatch is the number of URLs accepted by POST /unfurl, 20 by default
+	MaxBatch int
+}
+
+// Server is an http.Handler serving the unfurl endpoints
+type Server struct {
+	config Config
+	parser *parser.Parser
+	slots  chan struct{}
+	mux    *http.ServeMux
+}
+
+// New returns a Server configured by config
+func New(config Config) *Server {
+	if config.MaxConcurrency <= 0 {
+		config.MaxConcurrency = defaultMaxConcurrency
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.MaxBatch <= 0 {
+		config.MaxBatch = defaultMaxBatch
+	}
+
+	s := &Server{
+		config: config,
+		parser: parser.New(config.Options...),
+		slots:  make(chan struct{}, config.MaxConcurrency),
+		mux:    http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/unfurl", s.handleUnfurl)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Error is the body of a failed response and of failed batch items
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// UpstreamStatus is the status code of the page when it was not 2xx
+	UpstreamStatus int `json:"upstream_status,omitempty"`
+
+	status int
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type errorResponse struct {
+	Error *Error `json:"error"`
+}
+
+type batchRequest struct {
+	URLs []string `json:"urls"`
+	View string   `json:"view"`
+}
+
+type batchItem struct {
+	URL    string      `json:"url"`
+	Result interface{} `json:"result,omitempty"`
+	Error  *Error      `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []*batchItem `json:"results"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleUnfurl(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		value, err := s.unfurl(r.Context(), r.URL.Query().Get("url"), r.URL.Query().Get("view"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, value)
+	case http.MethodPost:
+		s.handleBatch(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, &Error{Code: "method_not_allowed", Message: "use GET or POST", status: http.StatusMethodNotAllowed})
+	}
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(&req); err != nil {
+		writeError(w, &Error{Code: "invalid_request", Message: err.Error(), status: http.StatusBadRequest})
+		return
+	}
+	if len(req.URLs) == 0 {
+		writeError(w, &Error{Code: "invalid_request", Message: "urls is empty", status: http.StatusBadRequest})
+		return
+	}
+	if len(req.URLs) > s.config.MaxBatch {
+		writeError(w, &Error{Code: "batch_too_large", Message: "too many urls", status: http.StatusRequestEntityTooLarge})
+		return
+	}
+
+	resp := &batchResponse{Results: make([]*batchItem, len(req.URLs))}
+	var wg sync.WaitGroup
+	for i, target := range req.URLs {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			item := &batchItem{URL: target}
+			value, err := s.unfurl(r.Context(), target, req.View)
+			if err != nil {
+				item.Error = err
+			} else {
+				item.Result = value
+			}
+			resp.Results[i] = item
+		}(i, target)
+	}
+	wg.Wait()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// unfurl parses target and returns the Result, or its Preview when view is "preview"
+func (s *Server) unfurl(ctx context.Context, target, view string) (interface{}, *Error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, &Error{Code: "invalid_url", Message: "url must be an absolute http or https URL", status: http.StatusBadRequest}
+	}
+	if view != "" && view != "result" && view != "preview" {
+		return nil, &Error{Code: "invalid_view", Message: "view must be result or preview", status: http.StatusBadRequest}
+	}
+
+	// The timeout covers the wait for a slot, so requests cannot queue longer than it
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return nil, &Error{Code: "overloaded", Message: "no fetch slot became available", status: http.StatusServiceUnavailable}
+	}
+
+	result, err := s.parser.ParseURL(ctx, u.String())
+	if err != nil {
+		return nil, toError(err)
+	}
+	if view == "preview" {
+		return result.Preview(), nil
+	}
+	return result, nil
+}
+
+// toError maps fetch and parse failures to an error response
+func toError(err error) *Error {
+	e := &Error{Message: err.Error()}
+	var statusErr *parser.HTTPStatusError
+	var blockedErr *parser.BlockedError
+	switch {
+	case errors.As(err, &blockedErr):
+		e.Code, e.status = "blocked", http.StatusForbidden
+	case errors.As(err, &statusErr):
+		e.Code, e.status, e.UpstreamStatus = "upstream_status", http.StatusBadGateway, statusErr.StatusCode
+	case errors.Is(err, context.DeadlineExceeded):
+		e.Code, e.status = "timeout", http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		e.Code, e.status = "canceled", http.StatusServiceUnavailable
+	case errors.Is(err, parser.ErrNotHTML):
+		e.Code, e.status = "not_html", http.StatusUnprocessableEntity
+	case errors.Is(err, parser.ErrBodyTooLarge):
+		e.Code, e.status = "body_too_large", http.StatusUnprocessableEntity
+	case errors.Is(err, parser.ErrTooManyRedirects):
+		e.Code, e.status = "too_many_redirects", http.StatusBadGateway
+	default:
+		e.Code, e.status = "fetch_failed", http.StatusBadGateway
+	}
+	return e
+}
+
+func writeError(w http.ResponseWriter, err *Error) {
+	writeJSON(w, err.status, &errorResponse{Error: err})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,146 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ammit/go-metaparser/server"
+)
+
+const page = `
+<html>
+<head>
+	<title>Go Meta Parser</title>
+	<meta property="og:title" content="sample title" />
+</head>
+`
+
+func upstream() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+type response struct {
+	Title   string `json:"title"`
+	Results []struct {
+		URL    string                 `json:"url"`
+		Result map[string]interface{} `json:"result"`
+		Error  *server.Error          `json:"error"`
+	} `json:"results"`
+	Error *server.Error `json:"error"`
+}
+
+func do(t *testing.T, handler http.Handler, method, target, body string) (int, *response) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	resp := &response{}
+	if err := json.Unmarshal(rec.Body.Bytes(), resp); err != nil {
+		t.Fatalf("%s %s: invalid json %q", method, target, rec.Body.String())
+	}
+	return rec.Code, resp
+}
+
+func TestServerUnfurl(t *testing.T) {
+	ts := upstream()
+	defer ts.Close()
+	s := server.New(server.Config{Timeout: 100 * time.Millisecond})
+
+	code, resp := do(t, s, http.MethodGet, "/unfurl?url="+url.QueryEscape(ts.URL+"/page"), "")
+	if code != http.StatusOK || resp.Title != "Go Meta Parser" {
+		t.Errorf("GET /unfurl returned %d %+v", code, resp)
+	}
+
+	code, resp = do(t, s, http.MethodGet, "/unfurl?view=preview&url="+url.QueryEscape(ts.URL+"/page"), "")
+	if code != http.StatusOK || resp.Title != "sample title" {
+		t.Errorf("GET /unfurl?view=preview returned %d %+v", code, resp)
+	}
+
+	code, resp = do(t, s, http.MethodGet, "/unfurl?url="+url.QueryEscape(ts.URL+"/missing"), "")
+	if code != http.StatusBadGateway || resp.Error == nil || resp.Error.Code != "upstream_status" || resp.Error.UpstreamStatus != http.StatusNotFound {
+		t.Errorf("upstream 404 mapped incorrectly: %d %+v", code, resp.Error)
+	}
+
+	code, resp = do(t, s, http.MethodGet, "/unfurl?url="+url.QueryEscape(ts.URL+"/slow"), "")
+	if code != http.StatusGatewayTimeout || resp.Error == nil || resp.Error.Code != "timeout" {
+		t.Errorf("timeout mapped incorrectly: %d %+v", code, resp.Error)
+	}
+
+	code, resp = do(t, s, http.MethodGet, "/unfurl?url=ftp://example.com", "")
+	if code != http.StatusBadRequest || resp.Error == nil || resp.Error.Code != "invalid_url" {
+		t.Errorf("invalid url mapped incorrectly: %d %+v", code, resp.Error)
+	}
+}
+
+func TestServerUnfurlBatch(t *testing.T) {
+	ts := upstream()
+	defer ts.Close()
+	s := server.New(server.Config{MaxConcurrency: 1, MaxBatch: 2})
+
+	body := `{"urls": ["` + ts.URL + `/page", "` + ts.URL + `/missing"], "view": "preview"}`
+	code, resp := do(t, s, http.MethodPost, "/unfurl", body)
+	if code != http.StatusOK || len(resp.Results) != 2 {
+		t.Fatalf("POST /unfurl returned %d %+v", code, resp)
+	}
+	if resp.Results[0].Result["title"] != "sample title" || resp.Results[0].Error != nil {
+		t.Errorf("batch item parsed incorrectly: %+v", resp.Results[0])
+	}
+	if resp.Results[1].Error == nil || resp.Results[1].Error.UpstreamStatus != http.StatusNotFound {
+		t.Errorf("batch item error mapped incorrectly: %+v", resp.Results[1])
+	}
+
+	body = `{"urls": ["` + ts.URL + `/page", "` + ts.URL + `/page", "` + ts.URL + `/page"]}`
+	code, resp = do(t, s, http.MethodPost, "/unfurl", body)
+	if code != http.StatusRequestEntityTooLarge || resp.Error == nil || resp.Error.Code != "batch_too_large" {
+		t.Errorf("oversized batch mapped incorrectly: %d %+v", code, resp.Error)
+	}
+}
+
+func TestServerUnfurlOverloaded(t *testing.T) {
+	ts := upstream()
+	defer ts.Close()
+	s := server.New(server.Config{MaxConcurrency: 1, Timeout: 100 * time.Millisecond, MaxBatch: 5})
+
+	// Queued one after the other the batch would take five timeouts
+	body := `{"urls": ["` + strings.Repeat(ts.URL+`/slow", "`, 4) + ts.URL + `/slow"]}`
+	start := time.Now()
+	code, resp := do(t, s, http.MethodPost, "/unfurl", body)
+	if code != http.StatusOK || len(resp.Results) != 5 {
+		t.Fatalf("POST /unfurl returned %d %+v", code, resp)
+	}
+	for _, item := range resp.Results {
+		if item.Error == nil || (item.Error.Code != "overloaded" && item.Error.Code != "timeout") {
+			t.Errorf("blocked batch item mapped incorrectly: %+v", item.Error)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("waiting for a slot must count against the timeout, the batch took %v", elapsed)
+	}
+}
+
+func TestServerHealth(t *testing.T) {
+	s := server.New(server.Config{})
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"ok"`) {
+		t.Errorf("GET /healthz returned %d %s", rec.Code, rec.Body.String())
+	}
+}
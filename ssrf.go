@@ -0,0 +1,381 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// BlockedError is returned when WithSafeDialer refuses to fetch a URL
+type BlockedError struct {
+	// Host is the host, or host:port, that was refused
+	Host string
+	// IP is the refused address, nil when the scheme or the port was refused
+	IP     net.IP
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	if e.IP != nil {
+		return fmt.Sprintf("fetching %s is not allowed: %s address %s", e.Host, e.Reason, e.IP)
+	}
+	return fmt.Sprintf("fetching %s is not allowed: %s", e.Host, e.Reason)
+}
+
+var defaultAllowedPorts = []int{80, 443}
+
+// Ranges that must never be reached from an unfurl, IPv4-mapped IPv6 addresses are checked as IPv4
+var blockedNetworks = []struct {
+	cidr   string
+	reason string
+}{
+	{"0.0.0.0/8", "unspecified"},
+	{"10.0.0.0/8", "private"},
+	{"100.64.0.0/10", "shared"},
+	{"127.0.0.0/8", "loopback"},
+	{"169.254.0.0/16", "link-local"},
+	{"172.16.0.0/12", "private"},
+	{"192.0.0.0/24", "reserved"},
+	{"192.0.2.0/24", "documentation"},
+	{"192.168.0.0/16", "private"},
+	{"198.18.0.0/15", "benchmarking"},
+	{"198.51.100.0/24", "documentation"},
+	{"203.0.113.0/24", "documentation"},
+	{"224.0.0.0/4", "multicast"},
+	{"240.0.0.0/4", "reserved"},
+	{"::/96", "ipv4-compatible"},
+	{"fc00::/7", "private"},
+	{"fe80::/10", "link-local"},
+	{"fec0::/10", "site-local"},
+	{"ff00::/8", "multicast"},
+	{"2001:db8::/32", "documentation"},
+}
+
+var blockedNets = func() []*net.IPNet {
+	nets := make([]*net.IPNet, len(blockedNetworks))
+	for i, network := range blockedNetworks {
+		_, nets[i], _ = net.ParseCIDR(network.cidr)
+	}
+	return nets
+}()
+
+// IPv6 prefixes that embed an IPv4 address: NAT64 and 6to4
+var (
+	_, nat64Net, _  = net.ParseCIDR("64:ff9b::/96")
+	_, sixToFour, _ = net.ParseCIDR("2002::/16")
+)
+
+// blockedReason says why ip must not be dialed, or returns "" if it may
+func blockedReason(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if nat64Net.Contains(ip) {
+		return blockedReason(ip[12:16])
+	} else if sixToFour.Contains(ip) {
+		return blockedReason(ip[2:6])
+	}
+	for i, network := range blockedNets {
+		if network.Contains(ip) {
+			return blockedNetworks[i].reason
+		}
+	}
+	return ""
+}
+
+// safeDialer only connects to public addresses on allowed ports. It resolves hostnames itself and
+// dials the checked address, so a second DNS answer cannot point the connection elsewhere.
+type safeDialer struct {
+	dialer *net.Dialer
+	ports  map[int]bool
+}
+
+func newSafeDialer(ports []int) *safeDialer {
+	if len(ports) == 0 {
+		ports = defaultAllowedPorts
+	}
+	d := &safeDialer{
+		dialer: &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		ports:  make(map[int]bool),
+	}
+	for _, port := range ports {
+		d.ports[port] = true
+	}
+	return d
+}
+
+// checkURL refuses schemes other than http and https and ports outside of the allowlist
+func (d *safeDialer) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &BlockedError{Host: u.Host, Reason: "scheme " + strconv.Quote(u.Scheme) + " not allowed"}
+	}
+	if p, err := strconv.Atoi(urlPort(u)); err != nil || !d.ports[p] {
+		return &BlockedError{Host: u.Host, Reason: "port " + urlPort(u) + " not allowed"}
+	}
+	return nil
+}
+
+func (d *safeDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if p, err := strconv.Atoi(port); err != nil || !d.ports[p] {
+		return nil, &BlockedError{Host: addr, Reason: "port " + port + " not allowed"}
+	}
+
+	ips, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error = &net.AddrError{Err: "no addresses", Addr: host}
+	for _, ip := range ips {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// resolve returns the addresses of host, refusing it if any of them is internal
+func (d *safeDialer) resolve(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	// A single internal answer is enough to refuse the host
+	for _, ip := range ips {
+		if reason := blockedReason(ip.IP); reason != "" {
+			return nil, &BlockedError{Host: host, IP: ip.IP, Reason: reason}
+		}
+	}
+	return ips, nil
+}
+
+// urlPort returns the port of u, the default one of its scheme when it has none
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	return map[string]string{"http": "80", "https": "443", "socks5": "1080", "socks5h": "1080"}[u.Scheme]
+}
+
+type pinKey struct{}
+
+// pin is the proxy picked for a request and the checked address of its target
+type pin struct {
+	proxy *url.URL
+	addr  string
+}
+
+// safeTransport sends every request through the safe dialer. A proxy would resolve the target
+// itself, so when one is picked the target is resolved and checked here and the proxy is only
+// given the checked address: in the request line of plain http requests, in a CONNECT or SOCKS5
+// tunnel otherwise. TLS still verifies the host name, and no other address than the proxy of the
+// request is dialed without checks.
+type safeTransport struct {
+	dialer *safeDialer
+	proxy  func(*http.Request) (*url.URL, error)
+	// direct dials targets with the safe dialer
+	direct *http.Transport
+	// forward sends plain http requests to an http or https proxy
+	forward *http.Transport
+	// tunnel dials the target through the proxy
+	tunnel *http.Transport
+}
+
+func newSafeTransport(d *safeDialer, t *http.Transport) *safeTransport {
+	st := &safeTransport{dialer: d, proxy: t.Proxy}
+	clone := func(dial func(context.Context, string, string) (net.Conn, error)) *http.Transport {
+		c := t.Clone()
+		c.Proxy = nil
+		c.DialContext = dial
+		// A custom TLS dialer would connect without the checks
+		c.DialTLS = nil
+		c.DialTLSContext = nil
+		return c
+	}
+	st.direct = clone(d.DialContext)
+	if st.proxy != nil {
+		st.forward = clone(st.dialProxy)
+		st.forward.Proxy = pinnedProxy
+		st.tunnel = clone(st.dialTunnel)
+	}
+	return st
+}
+
+func (st *safeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := st.roundTrip(req)
+	if resp != nil {
+		// The request may have been rewritten to the checked address
+		resp.Request = req
+	}
+	return resp, err
+}
+
+func (st *safeTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	if err := st.dialer.checkURL(req.URL); err != nil {
+		closeRequestBody(req)
+		return nil, err
+	}
+	var proxyURL *url.URL
+	if st.proxy != nil {
+		var err error
+		if proxyURL, err = st.proxy(req); err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+	}
+	if proxyURL == nil {
+		return st.direct.RoundTrip(req)
+	}
+
+	ips, err := st.dialer.resolve(req.Context(), req.URL.Hostname())
+	if err != nil {
+		closeRequestBody(req)
+		return nil, err
+	}
+	p := &pin{proxy: proxyURL, addr: net.JoinHostPort(ips[0].IP.String(), urlPort(req.URL))}
+	pinned := req.WithContext(context.WithValue(req.Context(), pinKey{}, p))
+	if req.URL.Scheme == "http" && (proxyURL.Scheme == "http" || proxyURL.Scheme == "https") {
+		// The proxy connects to the host of the request line, the Host header keeps the name
+		u := *req.URL
+		u.Host = p.addr
+		pinned.URL = &u
+		if pinned.Host == "" {
+			pinned.Host = req.URL.Host
+		}
+		return st.forward.RoundTrip(pinned)
+	}
+	return st.tunnel.RoundTrip(pinned)
+}
+
+func (st *safeTransport) CloseIdleConnections() {
+	for _, t := range []*http.Transport{st.direct, st.forward, st.tunnel} {
+		if t != nil {
+			t.CloseIdleConnections()
+		}
+	}
+}
+
+// pinnedProxy is the proxy function of the forward transport, it only sends pinned requests
+func pinnedProxy(req *http.Request) (*url.URL, error) {
+	p, _ := req.Context().Value(pinKey{}).(*pin)
+	if p == nil {
+		return nil, &BlockedError{Host: req.URL.Host, Reason: "target not checked"}
+	}
+	return p.proxy, nil
+}
+
+// dialProxy dials the proxy picked for the request and nothing else
+func (st *safeTransport) dialProxy(ctx context.Context, network, addr string) (net.Conn, error) {
+	p, _ := ctx.Value(pinKey{}).(*pin)
+	if p == nil || addr != net.JoinHostPort(p.proxy.Hostname(), urlPort(p.proxy)) {
+		return nil, &BlockedError{Host: addr, Reason: "not the proxy of the request"}
+	}
+	return st.dialer.dialer.DialContext(ctx, network, addr)
+}
+
+// dialTunnel connects to the checked address of the request through its proxy
+func (st *safeTransport) dialTunnel(ctx context.Context, network, addr string) (net.Conn, error) {
+	p, _ := ctx.Value(pinKey{}).(*pin)
+	if p == nil {
+		return nil, &BlockedError{Host: addr, Reason: "target not checked"}
+	}
+
+	switch p.proxy.Scheme {
+	case "socks5", "socks5h":
+		dialer, err := proxy.FromURL(p.proxy, st.dialer.dialer)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.(proxy.ContextDialer).DialContext(ctx, network, p.addr)
+	case "http", "https":
+		conn, err := st.dialer.dialer.DialContext(ctx, network, net.JoinHostPort(p.proxy.Hostname(), urlPort(p.proxy)))
+		if err != nil {
+			return nil, err
+		}
+		// Unblock the handshake and the CONNECT exchange when ctx is done
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+			case <-done:
+			}
+		}()
+		tunnel, err := st.connect(conn, p)
+		close(done)
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tunnel, nil
+	}
+	return nil, &BlockedError{Host: p.proxy.Host, Reason: "proxy scheme " + strconv.Quote(p.proxy.Scheme) + " not supported"}
+}
+
+// connect asks an http or https proxy for a tunnel to the checked address
+func (st *safeTransport) connect(conn net.Conn, p *pin) (net.Conn, error) {
+	if p.proxy.Scheme == "https" {
+		config := &tls.Config{}
+		if st.tunnel.TLSClientConfig != nil {
+			config = st.tunnel.TLSClientConfig.Clone()
+		}
+		config.ServerName = p.proxy.Hostname()
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	header := st.tunnel.ProxyConnectHeader.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	if user := p.proxy.User; user != nil {
+		password, _ := user.Password()
+		header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: p.addr}, Host: p.addr, Header: header}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy refused the tunnel to %s: %s", p.addr, resp.Status)
+	}
+	return conn, nil
+}
+
+// refusingTransport replaces a RoundTripper the safe dialer cannot check, every request fails
+type refusingTransport struct{}
+
+func (refusingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	closeRequestBody(req)
+	return nil, &BlockedError{Host: req.URL.Host, Reason: "addresses cannot be checked with a transport other than *http.Transport"}
+}
+
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -1,6 +1,9 @@
 package parser
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 type player struct {
 	URL    string `json:"url"`
@@ -9,11 +12,14 @@ type player struct {
 	Stream string `json:"stream"`
 }
 
-type app struct {
-	Name string `json:"name"`
-	ID   int64  `json:"id"`
-	URL  string `json:"url"`
-	Type string `json:"type"`
+// TwitterApp groups the twitter:app:name, twitter:app:id and twitter:app:url tags of a platform
+type TwitterApp struct {
+	// Platform is the suffix of the tags, such as iphone, ipad or googleplay
+	Platform string `json:"platform"`
+	Name     string `json:"name"`
+	// ID is an App Store id or a Google Play package such as com.example.app
+	ID  string `json:"id"`
+	URL string `json:"url"`
 }
 
 // Twitter type in Open Graph
@@ -28,163 +34,85 @@ type Twitter struct {
 	Image       string `json:"image"`
 	ImageAlt    string `json:"image_alt"`
 	Player      player `json:"player"`
-	Apps        []app  `json:"apps"`
+	// Apps are in the order their platforms first appear
+	Apps []*TwitterApp `json:"apps"`
 }
 
-func (p *Parser) ensureApp(appType string) {
-	exist := false
-	for i := 0; i < len(p.Twitter.Apps); i++ {
-		attr := &p.Twitter.Apps[i]
-		if attr.Type == appType {
-			exist = true
-			break
+// Lookup returns the app of the first platform that has one, e.g. Lookup("googleplay")
+func (twitter *Twitter) Lookup(platforms ...string) *TwitterApp {
+	for _, platform := range platforms {
+		for _, app := range twitter.Apps {
+			if app.Platform == platform {
+				return app
+			}
 		}
 	}
+	return nil
+}
+
+func isTwitterAppProperty(property string) bool {
+	return strings.HasPrefix(property, "twitter:app:")
+}
 
-	if !exist {
-		p.Twitter.Apps = append(p.Twitter.Apps, app{
-			Type: appType,
-		})
+// parseTwitterAppMeta reads twitter:app:<field>:<platform> for any platform
+func (result *Result) parseTwitterAppMeta(attrs map[string]string) {
+	parts := strings.SplitN(strings.TrimPrefix(attrs["property"], "twitter:app:"), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return
+	}
+	field, platform := parts[0], parts[1]
+	if field != "name" && field != "id" && field != "url" {
+		return
+	}
+
+	app := result.Twitter.Lookup(platform)
+	if app == nil {
+		app = &TwitterApp{Platform: platform}
+		result.Twitter.Apps = append(result.Twitter.Apps, app)
+	}
+	switch field {
+	case "name":
+		app.Name = attrs["content"]
+	case "id":
+		app.ID = strings.TrimSpace(attrs["content"])
+	case "url":
+		app.URL = attrs["content"]
 	}
 }
 
-func (p *Parser) parseTwitterMeta(attrs map[string]string) {
+func (result *Result) parseTwitterMeta(attrs map[string]string) {
 	switch attrs["property"] {
 	case "twitter:card":
-		p.Twitter.Card = attrs["content"]
+		result.Twitter.Card = attrs["content"]
 	case "twitter:site":
-		p.Twitter.Site = attrs["content"]
+		result.Twitter.Site = attrs["content"]
 	case "twitter:site:id":
-		p.Twitter.SiteID = attrs["content"]
+		result.Twitter.SiteID = attrs["content"]
 	case "twitter:creator":
-		p.Twitter.Creator = attrs["content"]
+		result.Twitter.Creator = attrs["content"]
 	case "twitter:creator:id":
-		p.Twitter.CreatorID = attrs["content"]
+		result.Twitter.CreatorID = attrs["content"]
 	case "twitter:description":
-		p.Twitter.Description = attrs["content"]
+		result.Twitter.Description = attrs["content"]
 	case "twitter:title":
-		p.Twitter.Title = attrs["content"]
+		result.Twitter.Title = attrs["content"]
 	case "twitter:image":
-		p.Twitter.Image = attrs["content"]
+		result.Twitter.Image = attrs["content"]
 	case "twitter:image:alt":
-		p.Twitter.ImageAlt = attrs["content"]
+		result.Twitter.ImageAlt = attrs["content"]
 	case "twitter:player":
-		p.Twitter.Player.URL = attrs["content"]
+		result.Twitter.Player.URL = attrs["content"]
 	case "twitter:player:height":
 		w, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.Twitter.Player.Height = w
+			result.Twitter.Player.Height = w
 		}
 	case "twitter:player:width":
 		w, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.Twitter.Player.Width = w
+			result.Twitter.Player.Width = w
 		}
 	case "twitter:player:stream":
-		p.Twitter.Player.Stream = attrs["content"]
-	case "twitter:app:name:iphone":
-		p.ensureApp("iphone")
-
-		for i := range p.Twitter.Apps {
-			attr := &p.Twitter.Apps[i]
-			if attr.Type == "iphone" {
-				attr.Name = attrs["content"]
-				break
-			}
-		}
-	case "twitter:app:url:iphone":
-		p.ensureApp("iphone")
-
-		for i := range p.Twitter.Apps {
-			attr := &p.Twitter.Apps[i]
-			if attr.Type == "iphone" {
-				attr.URL = attrs["content"]
-				break
-			}
-		}
-	case "twitter:app:id:iphone":
-		p.ensureApp("iphone")
-		w, err := strconv.ParseInt(attrs["content"], 10, 64)
-		if err == nil {
-			for i := range p.Twitter.Apps {
-				attr := &p.Twitter.Apps[i]
-				if attr.Type == "iphone" {
-					attr.ID = w
-					break
-				}
-			}
-		}
-	case "twitter:app:name:ipad":
-		p.ensureApp("ipad")
-
-		for i := range p.Twitter.Apps {
-			attr := &p.Twitter.Apps[i]
-			if attr.Type == "ipad" {
-				attr.Name = attrs["content"]
-				break
-			}
-		}
-	case "twitter:app:url:ipad":
-		p.ensureApp("ipad")
-
-		for i := range p.Twitter.Apps {
-			attr := &p.Twitter.Apps[i]
-			if attr.Type == "ipad" {
-				attr.URL = attrs["content"]
-				break
-			}
-		}
-	case "twitter:app:id:ipad":
-		w, err := strconv.ParseInt(attrs["content"], 10, 64)
-		if err == nil {
-			p.ensureApp("ipad")
-
-			for i := range p.Twitter.Apps {
-				attr := &p.Twitter.Apps[i]
-				if attr.Type == "ipad" {
-					attr.ID = w
-					break
-				}
-			}
-		}
-	case "twitter:app:name:googleplay":
-		p.ensureApp("googleplay")
-
-		for i := range p.Twitter.Apps {
-			attr := &p.Twitter.Apps[i]
-			if attr.Type == "googleplay" {
-				attr.Name = attrs["content"]
-				break
-			}
-		}
-	case "twitter:app:url:googleplay":
-		p.ensureApp("googleplay")
-
-		for i := range p.Twitter.Apps {
-			attr := &p.Twitter.Apps[i]
-			if attr.Type == "googleplay" {
-				attr.URL = attrs["content"]
-				break
-			}
-		}
-	case "twitter:app:id:googleplay":
-		w, err := strconv.ParseInt(attrs["content"], 10, 64)
-		if err == nil {
-			p.ensureApp("googleplay")
-
-			for i := range p.Twitter.Apps {
-				attr := &p.Twitter.Apps[i]
-				if attr.Type == "googleplay" {
-					attr.ID = w
-					break
-				}
-			}
-		}
+		result.Twitter.Player.Stream = attrs["content"]
 	}
 }
-
-// TODO: mapping if not exisits
-// twitter:card <= og:type
-// twitter:description <= og:description
-// twitter:title <= og:title
-// twitter:image <= og:image
@@ -25,78 +25,78 @@ type Video struct {
 	Tags        []string `json:"tags"`
 }
 
-func (p *Parser) ensureVideo() {
-	if len(p.Videos) > 0 {
+func (result *Result) ensureVideo() {
+	if len(result.Videos) > 0 {
 		return
 	}
-	p.Videos = append(p.Videos, &Video{})
+	result.Videos = append(result.Videos, &Video{})
 }
 
-func (p *Parser) ensureVideoActor() {
-	if len(p.Videos[len(p.Videos)-1].Actors) > 0 {
+func (result *Result) ensureVideoActor() {
+	if len(result.Videos[len(result.Videos)-1].Actors) > 0 {
 		return
 	}
 
-	p.Videos[len(p.Videos)-1].Actors = append(p.Videos[len(p.Videos)-1].Actors, &actor{})
+	result.Videos[len(result.Videos)-1].Actors = append(result.Videos[len(result.Videos)-1].Actors, &actor{})
 }
 
-func (p *Parser) parseVideoMeta(attrs map[string]string) {
+func (result *Result) parseVideoMeta(attrs map[string]string) {
 	switch attrs["property"] {
 	case "og:video":
-		if len(p.Videos) > 0 && len(p.Videos[len(p.Videos)-1].URL) == 0 {
-			p.Videos[len(p.Videos)-1].URL = attrs["content"]
+		if len(result.Videos) > 0 && len(result.Videos[len(result.Videos)-1].URL) == 0 {
+			result.Videos[len(result.Videos)-1].URL = attrs["content"]
 		} else {
-			p.Videos = append(p.Videos, &Video{URL: attrs["content"]})
+			result.Videos = append(result.Videos, &Video{URL: attrs["content"]})
 		}
 	case "og:video:url":
-		p.ensureVideo()
-		p.Videos[len(p.Videos)-1].URL = attrs["content"]
+		result.ensureVideo()
+		result.Videos[len(result.Videos)-1].URL = attrs["content"]
 	case "og:video:secure_url":
-		p.ensureVideo()
-		p.Videos[len(p.Videos)-1].SecureURL = attrs["content"]
+		result.ensureVideo()
+		result.Videos[len(result.Videos)-1].SecureURL = attrs["content"]
 	case "og:video:type":
-		p.ensureVideo()
-		p.Videos[len(p.Videos)-1].Type = attrs["content"]
+		result.ensureVideo()
+		result.Videos[len(result.Videos)-1].Type = attrs["content"]
 	case "og:video:width":
 		w, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.ensureVideo()
-			p.Videos[len(p.Videos)-1].Width = w
+			result.ensureVideo()
+			result.Videos[len(result.Videos)-1].Width = w
 		}
 	case "og:video:height":
 		h, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.ensureVideo()
-			p.Videos[len(p.Videos)-1].Height = h
+			result.ensureVideo()
+			result.Videos[len(result.Videos)-1].Height = h
 		}
 	case "video:actor":
-		p.ensureVideo()
-		p.ensureVideoActor()
-		p.Videos[len(p.Videos)-1].Actors[len(p.Videos[len(p.Videos)-1].Actors)-1].URL = attrs["content"]
+		result.ensureVideo()
+		result.ensureVideoActor()
+		result.Videos[len(result.Videos)-1].Actors[len(result.Videos[len(result.Videos)-1].Actors)-1].URL = attrs["content"]
 	case "video:actor:role":
-		p.ensureVideo()
-		p.ensureVideoActor()
-		p.Videos[len(p.Videos)-1].Actors[len(p.Videos[len(p.Videos)-1].Actors)-1].Role = attrs["content"]
+		result.ensureVideo()
+		result.ensureVideoActor()
+		result.Videos[len(result.Videos)-1].Actors[len(result.Videos[len(result.Videos)-1].Actors)-1].Role = attrs["content"]
 	case "video:director":
-		p.ensureVideo()
-		p.Videos[len(p.Videos)-1].Director = attrs["content"]
+		result.ensureVideo()
+		result.Videos[len(result.Videos)-1].Director = attrs["content"]
 	case "video:writer":
-		p.ensureVideo()
-		p.Videos[len(p.Videos)-1].Writer = attrs["content"]
+		result.ensureVideo()
+		result.Videos[len(result.Videos)-1].Writer = attrs["content"]
 	case "video:duration":
 		h, err := strconv.ParseInt(attrs["content"], 10, 64)
 		if err == nil {
-			p.ensureVideo()
-			p.Videos[len(p.Videos)-1].Duration = h
+			result.ensureVideo()
+			result.Videos[len(result.Videos)-1].Duration = h
 		}
 	case "video:release_date":
-		p.ensureVideo()
-		p.Videos[len(p.Videos)-1].ReleaseDate = attrs["content"]
+		result.ensureVideo()
+		result.Videos[len(result.Videos)-1].ReleaseDate = attrs["content"]
 	case "video:tag":
-		p.ensureVideo()
-		p.Videos[len(p.Videos)-1].Tags = append(p.Videos[len(p.Videos)-1].Tags, attrs["content"])
+		result.ensureVideo()
+		result.Videos[len(result.Videos)-1].Tags = append(result.Videos[len(result.Videos)-1].Tags, attrs["content"])
 	case "video:series":
-		p.ensureVideo()
-		p.Videos[len(p.Videos)-1].Series = attrs["content"]
+		result.ensureVideo()
+		result.Videos[len(result.Videos)-1].Series = attrs["content"]
 	}
 }